@@ -0,0 +1,91 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/crowdstrike/gcp-os-policy/internal/policy"
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the cs-policy config file. It holds settings that are too verbose for flags.
+//
+//	hooks:
+//	  rhel:
+//	    preInstall:
+//	      validate: |
+//	        test -f /etc/pki/ca-trust/source/anchors/proxy.pem && exit 100 || exit 101
+//	      enforce: |
+//	        curl -sSfo /etc/pki/ca-trust/source/anchors/proxy.pem https://example.com/proxy.pem
+//	        update-ca-trust && exit 100 || exit 101
+//	installParams:
+//	  linux: --tags=default
+//	  rhel9: --tags=rhel --aph=proxy.example.com --app=8080
+//	staging:
+//	  linux:
+//	    dir: /var/lib/falcon-staging
+//	    mode: "750"
+//	    cleanup: true
+type Config struct {
+	// Hooks are keyed by os key (e.g. rhel9), os family (e.g. rhel, ubuntu, windows) or
+	// platform (linux, windows).
+	Hooks map[string]policy.Hooks `yaml:"hooks"`
+	// InstallParams are keyed the same way as Hooks. The linux and windows values are used when
+	// --linux-install-params or --windows-install-params are not set.
+	InstallParams map[string]string `yaml:"installParams"`
+	// Staging configures where and how the installers are staged on the VMs, keyed by platform
+	// (linux, windows).
+	Staging map[string]policy.Staging `yaml:"staging"`
+}
+
+// Load reads the yaml or json config file at path. An empty path returns an empty Config.
+func Load(path string) (Config, error) {
+	var c Config
+
+	if path == "" {
+		return c, nil
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return c, err
+	}
+
+	dec := yaml.NewDecoder(bytes.NewReader(b))
+	dec.KnownFields(true)
+	if err := dec.Decode(&c); err != nil && !errors.Is(err, io.EOF) {
+		return c, fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+
+	osKeys := policy.OSKeys()
+	platforms := []string{policy.PlatformLinux, policy.PlatformWindows}
+	for _, section := range []struct {
+		name    string
+		keys    []string
+		allowed []string
+	}{
+		{name: "hooks", keys: slices.Collect(maps.Keys(c.Hooks)), allowed: osKeys},
+		{name: "installParams", keys: slices.Collect(maps.Keys(c.InstallParams)), allowed: osKeys},
+		{name: "staging", keys: slices.Collect(maps.Keys(c.Staging)), allowed: platforms},
+	} {
+		for _, key := range section.keys {
+			if !slices.Contains(section.allowed, key) {
+				return c, fmt.Errorf(
+					"invalid config file %s: unknown %s key %q: must be one of %s",
+					path,
+					section.name,
+					key,
+					strings.Join(section.allowed, ", "),
+				)
+			}
+		}
+	}
+
+	return c, nil
+}
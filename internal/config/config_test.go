@@ -0,0 +1,91 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/crowdstrike/gcp-os-policy/internal/policy"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name     string
+		contents string
+		wantErr  string
+		check    func(t *testing.T, c Config)
+	}{
+		{
+			name: "hooks",
+			contents: `
+hooks:
+  rhel:
+    preInstall:
+      validate: exit 100
+      enforce: exit 100
+  windows:
+    postConfigure:
+      validate: Exit 100
+      enforce: Exit 100
+      interpreter: POWERSHELL
+`,
+			check: func(t *testing.T, c Config) {
+				require.NotNil(t, c.Hooks["rhel"].PreInstall)
+				assert.Equal(t, "exit 100", c.Hooks["rhel"].PreInstall.Validate)
+				assert.Nil(t, c.Hooks["rhel"].PostConfigure)
+				assert.Equal(t, "POWERSHELL", c.Hooks["windows"].PostConfigure.Interpreter)
+			},
+		},
+		{
+			name:     "install params",
+			contents: "installParams:\n  linux: --tags=linux\n  rhel9: --tags=rhel9\n  ol: --tags=oracle\n",
+			check: func(t *testing.T, c Config) {
+				assert.Equal(
+					t,
+					map[string]string{"linux": "--tags=linux", "rhel9": "--tags=rhel9", "ol": "--tags=oracle"},
+					c.InstallParams,
+				)
+			},
+		},
+		{
+			name:     "staging",
+			contents: "staging:\n  linux:\n    dir: /var/lib/falcon\n    mode: 0750\n    cleanup: true\n  windows:\n    dir: D:\\Staging\n",
+			check: func(t *testing.T, c Config) {
+				assert.Equal(
+					t,
+					map[string]policy.Staging{
+						"linux":   {Dir: "/var/lib/falcon", Mode: "0750", Cleanup: true},
+						"windows": {Dir: `D:\Staging`},
+					},
+					c.Staging,
+				)
+			},
+		},
+		{name: "empty", contents: ""},
+		{name: "unknown install params key", contents: "installParams:\n  rhel6: --tags=old\n", wantErr: `unknown installParams key "rhel6"`},
+		{name: "unknown staging key", contents: "staging:\n  rhel9: {}\n", wantErr: `unknown staging key "rhel9": must be one of linux, windows`},
+		{name: "unknown key", contents: "hooks:\n  redhat: {}\n", wantErr: `unknown hooks key "redhat"`},
+		{name: "unknown field", contents: "hook: {}\n", wantErr: "field hook not found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			require.NoError(t, os.WriteFile(path, []byte(tt.contents), 0o600))
+
+			c, err := Load(path)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+
+			if tt.check != nil {
+				tt.check(t, c)
+			}
+		})
+	}
+}
@@ -0,0 +1,415 @@
+// Package doctor runs preflight checks for the environment cs-policy depends on.
+package doctor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os/exec"
+	"strings"
+
+	"cloud.google.com/go/storage"
+	"github.com/crowdstrike/gcp-os-policy/internal/falconutil"
+	"github.com/crowdstrike/gcp-os-policy/internal/gcputil"
+	"github.com/crowdstrike/gcp-os-policy/internal/logging"
+	"github.com/crowdstrike/gcp-os-policy/internal/tui"
+	"github.com/crowdstrike/gofalcon/falcon"
+	"github.com/crowdstrike/gofalcon/falcon/client"
+	"github.com/crowdstrike/gofalcon/falcon/client/sensor_download"
+	"golang.org/x/oauth2/clientcredentials"
+	"golang.org/x/oauth2/google"
+)
+
+// osPolicyAssignmentPermissions are required to create and monitor os policy assignments.
+var osPolicyAssignmentPermissions = []string{
+	"osconfig.osPolicyAssignments.create",
+	"osconfig.osPolicyAssignments.get",
+	"osconfig.osPolicyAssignments.update",
+}
+
+// bucketPermissions are required to stage sensor binaries in the bucket.
+var bucketPermissions = []string{
+	"storage.objects.create",
+	"storage.objects.delete",
+	"storage.objects.get",
+}
+
+// errSkipped is returned by checks that depend on an earlier check that failed.
+var errSkipped = errors.New("skipped")
+
+func skip(reason string) error {
+	return fmt.Errorf("%w: %s", errSkipped, reason)
+}
+
+// Config is the environment to check.
+type Config struct {
+	FalconConfig *falcon.ApiConfig
+	Bucket       string
+	// Projects are the projects to check. The gcloud cli's active project is used when empty.
+	Projects []string
+	Zones    []string
+	Logger   *slog.Logger
+}
+
+// Check is a single preflight check.
+type Check struct {
+	Name        string
+	Remediation string
+	// Optional checks are reported as warnings and do not fail the run.
+	Optional bool
+	Run      func(ctx context.Context) error
+}
+
+// Doctor runs checks in order, sharing the clients created along the way.
+type Doctor struct {
+	cfg Config
+
+	falconClient  *client.CrowdStrikeAPISpecification
+	gcpClient     *gcputil.Client
+	storageClient *storage.Client
+	projects      []string
+}
+
+func New(cfg Config) *Doctor {
+	return &Doctor{cfg: cfg}
+}
+
+// Run executes every check and writes the results to w. It returns false if a required check failed.
+func (d *Doctor) Run(ctx context.Context, w io.Writer) bool {
+	return d.run(ctx, w, d.Checks())
+}
+
+func (d *Doctor) run(ctx context.Context, w io.Writer, checks []Check) bool {
+	logger := logging.Or(d.cfg.Logger)
+	ok := true
+
+	for _, c := range checks {
+		err := c.Run(ctx)
+		logger.Debug("doctor check complete", "check", c.Name, "error", err)
+
+		switch {
+		case err == nil:
+			fmt.Fprintf(w, "  %s %s\n", tui.Green(tui.SuccessIcon), c.Name)
+		case errors.Is(err, errSkipped):
+			fmt.Fprintf(w, "  - %s (%s)\n", c.Name, err)
+		case c.Optional:
+			fmt.Fprintf(w, "  %s %s: %s\n", tui.Yellow(tui.WarningIcon), c.Name, err)
+			fmt.Fprintf(w, "      %s\n", c.Remediation)
+		default:
+			ok = false
+			fmt.Fprintf(w, "  %s %s: %s\n", tui.Red(tui.FailIcon), c.Name, err)
+			fmt.Fprintf(w, "      %s\n", c.Remediation)
+		}
+	}
+
+	return ok
+}
+
+// Checks returns the preflight checks in the order they run.
+func (d *Doctor) Checks() []Check {
+	return []Check{
+		{
+			Name:        "Falcon API authentication",
+			Remediation: "Verify the Falcon client id, client secret and cloud. See the Generate API Keys section of the README.",
+			Run:         d.checkFalconAuth,
+		},
+		{
+			Name:        "Falcon Sensor Download scope",
+			Remediation: "Add the Sensor Download (Read) scope to the API client in the Falcon console.",
+			Run:         d.checkFalconScope,
+		},
+		{
+			Name:        "Falcon CID lookup",
+			Remediation: "Provide the CID with --falcon-cid or the FALCON_CID environment variable.",
+			Run:         d.checkFalconCID,
+		},
+		{
+			Name:        "gcloud cli installed",
+			Remediation: "Install the gcloud cli: https://cloud.google.com/sdk/docs/install",
+			Run:         d.checkGcloud,
+		},
+		{
+			Name:        "GCP application default credentials",
+			Remediation: "Run `gcloud auth application-default login` or set GOOGLE_APPLICATION_CREDENTIALS.",
+			Run:         d.checkCredentials,
+		},
+		{
+			Name:        "GCP project",
+			Remediation: "Run `gcloud config set project <project>`.",
+			Run:         d.checkProject,
+		},
+		{
+			Name:        "OS Config API enabled",
+			Remediation: "Run `gcloud services enable osconfig.googleapis.com`.",
+			Run:         d.checkOsConfigAPI,
+		},
+		{
+			Name:        "VM Manager enabled",
+			Remediation: "Run `gcloud compute project-info add-metadata --metadata=enable-osconfig=TRUE`. Ignore if enable-osconfig is set on each instance instead.",
+			Optional:    true,
+			Run:         d.checkVMManager,
+		},
+		{
+			Name:        "OS Policy Assignment permissions",
+			Remediation: "Grant the OS Policy Assignment Admin role (roles/osconfig.osPolicyAssignmentAdmin) on the project.",
+			Run:         d.checkAssignmentPermissions,
+		},
+		{
+			Name:        "Compute zones",
+			Remediation: "Run `gcloud compute zones list` to see the zones available to the project.",
+			Run:         d.checkZones,
+		},
+		{
+			Name:        "Storage bucket exists",
+			Remediation: "Create the bucket with `gcloud storage buckets create gs://<bucket>` or provide an existing bucket.",
+			Run:         d.checkBucket,
+		},
+		{
+			Name:        "Storage bucket write access",
+			Remediation: "Grant the Storage Object Admin role (roles/storage.objectAdmin) on the bucket.",
+			Run:         d.checkBucketAccess,
+		},
+	}
+}
+
+func (d *Doctor) checkFalconAuth(ctx context.Context) error {
+	ac := d.cfg.FalconConfig
+	if ac == nil || ac.ClientId == "" || ac.ClientSecret == "" {
+		return errors.New("falcon client id and client secret are required")
+	}
+
+	c, err := falcon.NewClient(ac)
+	if err != nil {
+		return err
+	}
+
+	config := clientcredentials.Config{
+		ClientID:     ac.ClientId,
+		ClientSecret: ac.ClientSecret,
+		TokenURL:     "https://" + ac.Host() + "/oauth2/token",
+	}
+
+	if _, err := config.Token(ctx); err != nil {
+		return err
+	}
+
+	d.falconClient = c
+	return nil
+}
+
+func (d *Doctor) checkFalconScope(ctx context.Context) error {
+	if d.falconClient == nil {
+		return skip("falcon api authentication failed")
+	}
+
+	var limit int64 = 1
+	_, err := d.falconClient.SensorDownload.GetSensorInstallersByQuery(
+		&sensor_download.GetSensorInstallersByQueryParams{
+			Limit:   &limit,
+			Context: ctx,
+		},
+	)
+
+	var forbidden *sensor_download.GetSensorInstallersByQueryForbidden
+	if errors.As(err, &forbidden) {
+		return errors.New("api client is missing the Sensor Download (Read) scope")
+	}
+
+	return err
+}
+
+func (d *Doctor) checkFalconCID(_ context.Context) error {
+	if d.falconClient == nil {
+		return skip("falcon api authentication failed")
+	}
+
+	_, err := falconutil.CID(d.falconClient)
+	return err
+}
+
+func (d *Doctor) checkGcloud(_ context.Context) error {
+	_, err := exec.LookPath("gcloud")
+	return err
+}
+
+func (d *Doctor) checkCredentials(ctx context.Context) error {
+	creds, err := google.FindDefaultCredentials(ctx, "https://www.googleapis.com/auth/cloud-platform")
+	if err != nil {
+		return err
+	}
+
+	if _, err := creds.TokenSource.Token(); err != nil {
+		return err
+	}
+
+	d.gcpClient, err = gcputil.NewClient(ctx, d.cfg.Logger)
+	if err != nil {
+		return err
+	}
+
+	d.storageClient, err = gcputil.NewStorageClient(ctx, d.cfg.Logger)
+	return err
+}
+
+func (d *Doctor) checkProject(ctx context.Context) error {
+	for _, p := range d.cfg.Projects {
+		if p != "" {
+			d.projects = append(d.projects, p)
+		}
+	}
+
+	if len(d.projects) > 0 {
+		return nil
+	}
+
+	project, err := gcputil.ActiveProject(ctx)
+	if err != nil {
+		return err
+	}
+
+	d.projects = []string{project}
+	return nil
+}
+
+// forEachProject runs fn for every project, prefixing errors with the project when more than one is checked.
+func (d *Doctor) forEachProject(fn func(project string) error) error {
+	if d.gcpClient == nil || len(d.projects) == 0 {
+		return skip("gcp credentials or project unavailable")
+	}
+
+	var errs []error
+	for _, p := range d.projects {
+		if err := fn(p); err != nil {
+			if len(d.projects) > 1 {
+				err = fmt.Errorf("%s: %w", p, err)
+			}
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (d *Doctor) checkOsConfigAPI(ctx context.Context) error {
+	return d.forEachProject(func(project string) error {
+		enabled, err := d.gcpClient.ServiceEnabled(ctx, project, "osconfig.googleapis.com")
+		if err != nil {
+			return err
+		}
+
+		if !enabled {
+			return fmt.Errorf("osconfig.googleapis.com is not enabled in project %s", project)
+		}
+
+		return nil
+	})
+}
+
+func (d *Doctor) checkVMManager(ctx context.Context) error {
+	return d.forEachProject(func(project string) error {
+		value, ok, err := d.gcpClient.ProjectMetadata(ctx, project, "enable-osconfig")
+		if err != nil {
+			return err
+		}
+
+		if !ok || !strings.EqualFold(value, "true") {
+			return fmt.Errorf("project metadata enable-osconfig is not TRUE in project %s", project)
+		}
+
+		return nil
+	})
+}
+
+func (d *Doctor) checkAssignmentPermissions(ctx context.Context) error {
+	return d.forEachProject(func(project string) error {
+		missing, err := d.gcpClient.MissingPermissions(ctx, project, osPolicyAssignmentPermissions)
+		if err != nil {
+			return err
+		}
+
+		if len(missing) > 0 {
+			return fmt.Errorf("missing permissions: %s", strings.Join(missing, ", "))
+		}
+
+		return nil
+	})
+}
+
+func (d *Doctor) checkZones(ctx context.Context) error {
+	if len(d.cfg.Zones) == 0 {
+		return skip("no zones provided")
+	}
+
+	return d.forEachProject(func(project string) error {
+		available, err := d.gcpClient.Zones(ctx, project)
+		if err != nil {
+			return err
+		}
+
+		valid := make(map[string]bool, len(available))
+		for _, z := range available {
+			valid[z] = true
+		}
+
+		var invalid []string
+		for _, z := range d.cfg.Zones {
+			if !valid[z] {
+				invalid = append(invalid, z)
+			}
+		}
+
+		if len(invalid) > 0 {
+			return fmt.Errorf("unknown or unavailable zones: %s", strings.Join(invalid, ", "))
+		}
+
+		return nil
+	})
+}
+
+func (d *Doctor) checkBucket(ctx context.Context) error {
+	if d.cfg.Bucket == "" {
+		return skip("no bucket provided")
+	}
+
+	if d.storageClient == nil {
+		return skip("gcp credentials unavailable")
+	}
+
+	_, err := d.storageClient.Bucket(d.cfg.Bucket).Attrs(ctx)
+	return err
+}
+
+func (d *Doctor) checkBucketAccess(ctx context.Context) error {
+	if d.cfg.Bucket == "" {
+		return skip("no bucket provided")
+	}
+
+	if d.storageClient == nil {
+		return skip("gcp credentials unavailable")
+	}
+
+	granted, err := d.storageClient.Bucket(d.cfg.Bucket).IAM().TestPermissions(ctx, bucketPermissions)
+	if err != nil {
+		return err
+	}
+
+	have := make(map[string]bool, len(granted))
+	for _, p := range granted {
+		have[p] = true
+	}
+
+	var missing []string
+	for _, p := range bucketPermissions {
+		if !have[p] {
+			missing = append(missing, p)
+		}
+	}
+
+	if len(missing) > 0 {
+		return fmt.Errorf("missing permissions on bucket %s: %s", d.cfg.Bucket, strings.Join(missing, ", "))
+	}
+
+	return nil
+}
@@ -0,0 +1,197 @@
+package doctor
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"cloud.google.com/go/storage"
+	"github.com/crowdstrike/gcp-os-policy/internal/gcputil"
+	"github.com/crowdstrike/gcp-os-policy/internal/tui"
+	"github.com/crowdstrike/gofalcon/falcon/client"
+	"github.com/crowdstrike/gofalcon/falcon/client/sensor_download"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/compute/v1"
+	"google.golang.org/api/option"
+	"google.golang.org/api/serviceusage/v1"
+)
+
+// fakeSensorDownload rejects installer queries the way the Falcon API does for a client without the scope.
+type fakeSensorDownload struct {
+	sensor_download.ClientService
+}
+
+func (f *fakeSensorDownload) GetSensorInstallersByQuery(
+	_ *sensor_download.GetSensorInstallersByQueryParams,
+	_ ...sensor_download.ClientOption,
+) (*sensor_download.GetSensorInstallersByQueryOK, error) {
+	return nil, sensor_download.NewGetSensorInstallersByQueryForbidden()
+}
+
+// newTestDoctor returns a doctor whose gcp and storage clients are served responses by path.
+func newTestDoctor(t *testing.T, responses map[string]string) *Doctor {
+	t.Helper()
+	ctx := context.Background()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		body, ok := responses[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			io.WriteString(w, `{"error": {"code": 404, "message": "Not Found"}}`)
+			return
+		}
+		io.WriteString(w, body)
+	}))
+	t.Cleanup(srv.Close)
+
+	computeService, err := compute.NewService(
+		ctx,
+		option.WithEndpoint(srv.URL+"/compute/v1/"),
+		option.WithoutAuthentication(),
+	)
+	require.NoError(t, err)
+
+	serviceUsageService, err := serviceusage.NewService(
+		ctx,
+		option.WithEndpoint(srv.URL+"/"),
+		option.WithoutAuthentication(),
+	)
+	require.NoError(t, err)
+
+	storageClient, err := storage.NewClient(
+		ctx,
+		option.WithEndpoint(srv.URL+"/storage/v1/"),
+		option.WithoutAuthentication(),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { storageClient.Close() })
+
+	return &Doctor{
+		cfg:           Config{Bucket: "bucket"},
+		gcpClient:     &gcputil.Client{Compute: computeService, ServiceUsage: serviceUsageService},
+		storageClient: storageClient,
+		projects:      []string{"project"},
+	}
+}
+
+// checksNamed returns the doctor's checks with the given names.
+func checksNamed(d *Doctor, names ...string) []Check {
+	var checks []Check
+	for _, c := range d.Checks() {
+		if slices.Contains(names, c.Name) {
+			checks = append(checks, c)
+		}
+	}
+
+	return checks
+}
+
+func TestDoctor_Run(t *testing.T) {
+	const (
+		osConfigPath = "/v1/projects/project/services/osconfig.googleapis.com"
+		projectPath  = "/compute/v1/projects/project"
+		bucketIAM    = "/storage/v1/b/bucket/iam/testPermissions"
+	)
+
+	tests := []struct {
+		name         string
+		responses    map[string]string
+		falconClient *client.CrowdStrikeAPISpecification
+		checks       []string
+		expectedOK   bool
+		expectedOut  []string
+	}{
+		{
+			name:         "missing sensor download scope",
+			falconClient: &client.CrowdStrikeAPISpecification{SensorDownload: &fakeSensorDownload{}},
+			checks:       []string{"Falcon Sensor Download scope"},
+			expectedOK:   false,
+			expectedOut: []string{
+				tui.Red(tui.FailIcon) + " Falcon Sensor Download scope: api client is missing the Sensor Download (Read) scope",
+				"Add the Sensor Download (Read) scope to the API client in the Falcon console.",
+			},
+		},
+		{
+			name:       "os config api disabled",
+			responses:  map[string]string{osConfigPath: `{"state": "DISABLED"}`},
+			checks:     []string{"OS Config API enabled"},
+			expectedOK: false,
+			expectedOut: []string{
+				tui.Red(tui.FailIcon) + " OS Config API enabled: osconfig.googleapis.com is not enabled in project project",
+				"Run `gcloud services enable osconfig.googleapis.com`.",
+			},
+		},
+		{
+			name:       "os config api enabled",
+			responses:  map[string]string{osConfigPath: `{"state": "ENABLED"}`},
+			checks:     []string{"OS Config API enabled"},
+			expectedOK: true,
+			expectedOut: []string{
+				tui.Green(tui.SuccessIcon) + " OS Config API enabled",
+			},
+		},
+		{
+			name:       "bucket without write permission",
+			responses:  map[string]string{bucketIAM: `{"permissions": ["storage.objects.get"]}`},
+			checks:     []string{"Storage bucket write access"},
+			expectedOK: false,
+			expectedOut: []string{
+				tui.Red(tui.FailIcon) + " Storage bucket write access: missing permissions on bucket bucket: storage.objects.create, storage.objects.delete",
+				"Grant the Storage Object Admin role (roles/storage.objectAdmin) on the bucket.",
+			},
+		},
+		{
+			name: "vm manager disabled is a warning",
+			responses: map[string]string{
+				osConfigPath: `{"state": "ENABLED"}`,
+				projectPath:  `{"name": "project", "commonInstanceMetadata": {"items": [{"key": "enable-osconfig", "value": "FALSE"}]}}`,
+			},
+			checks:     []string{"OS Config API enabled", "VM Manager enabled"},
+			expectedOK: true,
+			expectedOut: []string{
+				tui.Green(tui.SuccessIcon) + " OS Config API enabled",
+				tui.Yellow(tui.WarningIcon) + " VM Manager enabled: project metadata enable-osconfig is not TRUE in project project",
+				"Run `gcloud compute project-info add-metadata --metadata=enable-osconfig=TRUE`.",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := newTestDoctor(t, tt.responses)
+			d.falconClient = tt.falconClient
+
+			checks := checksNamed(d, tt.checks...)
+			require.Len(t, checks, len(tt.checks))
+
+			var out bytes.Buffer
+			assert.Equal(t, tt.expectedOK, d.run(context.Background(), &out, checks))
+			for _, s := range tt.expectedOut {
+				assert.Contains(t, out.String(), s)
+			}
+		})
+	}
+}
+
+func TestDoctor_RunSkipsChecksWithoutClients(t *testing.T) {
+	d := New(Config{Bucket: "bucket"})
+	checks := checksNamed(
+		d,
+		"Falcon Sensor Download scope",
+		"OS Config API enabled",
+		"VM Manager enabled",
+		"Storage bucket write access",
+	)
+
+	var out bytes.Buffer
+	assert.True(t, d.run(context.Background(), &out, checks))
+	assert.Contains(t, out.String(), "- Falcon Sensor Download scope (skipped: falcon api authentication failed)")
+	assert.Contains(t, out.String(), "- OS Config API enabled (skipped: gcp credentials or project unavailable)")
+	assert.Contains(t, out.String(), "- Storage bucket write access (skipped: gcp credentials unavailable)")
+}
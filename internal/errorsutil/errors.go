@@ -2,12 +2,54 @@ package errorsutil
 
 import (
 	"fmt"
+	"runtime"
 	"strings"
+	"sync"
 
 	"github.com/crowdstrike/gcp-os-policy/internal/github"
+	"github.com/crowdstrike/gcp-os-policy/internal/redact"
 	"github.com/crowdstrike/gcp-os-policy/internal/tui"
 )
 
+type diagnostic struct {
+	key   string
+	value string
+}
+
+var (
+	lock          sync.RWMutex
+	redactor      = redact.New()
+	noIssueLink   bool
+	diagnostics   = []diagnostic{{key: "OS", value: runtime.GOOS + "/" + runtime.GOARCH}}
+	diagnosticIdx = map[string]int{"OS": 0}
+)
+
+// AddSensitive registers user supplied values (secrets, cids, buckets, projects) that are scrubbed from issue links.
+func AddSensitive(values ...string) {
+	redactor.Add(values...)
+}
+
+// SetDiagnostic records a value that is appended to the prefilled github issue.
+func SetDiagnostic(key string, value string) {
+	lock.Lock()
+	defer lock.Unlock()
+
+	if i, ok := diagnosticIdx[key]; ok {
+		diagnostics[i].value = value
+		return
+	}
+
+	diagnosticIdx[key] = len(diagnostics)
+	diagnostics = append(diagnostics, diagnostic{key: key, value: value})
+}
+
+// DisableIssueLink omits the prefilled github issue link from error messages.
+func DisableIssueLink(disable bool) {
+	lock.Lock()
+	defer lock.Unlock()
+	noIssueLink = disable
+}
+
 func DefaultError(explanation string, err error) string {
 	errMsg := strings.Builder{}
 	errMsg.WriteString(
@@ -18,18 +60,45 @@ func DefaultError(explanation string, err error) string {
 			err.Error(),
 		),
 	)
+
+	lock.RLock()
+	defer lock.RUnlock()
+
+	if noIssueLink {
+		return errMsg.String()
+	}
+
 	errMsg.WriteString(
 		"If you are unsure the cause of the error you can open a github issue for help.",
 	)
 	errMsg.WriteString(" Below is a link with the error prefilled.\n\n")
 	errMsg.WriteString(
 		fmt.Sprintf(
-			" %s make sure to remove any sensitive information\n\n",
+			" %s known secrets and identifiers have been redacted, review the issue before submitting\n\n",
 			tui.Yellow(fmt.Sprintf("%s%s %s:", tui.WarningIcon, tui.WarningIcon, "IMPORTANT")),
 		),
 	)
 
-	issueUrl, _ := github.IssueUrl(fmt.Sprintf("%s\n\n ```\n%s\n```", explanation, err.Error()))
+	issueUrl, _ := github.IssueUrl(issueBody(explanation, err))
 	errMsg.WriteString(issueUrl)
 	return errMsg.String()
 }
+
+// issueBody builds the redacted github issue body. Callers must hold lock.
+func issueBody(explanation string, err error) string {
+	body := strings.Builder{}
+	body.WriteString(
+		fmt.Sprintf(
+			"%s\n\n ```\n%s\n```\n\n",
+			redactor.String(explanation),
+			redactor.String(err.Error()),
+		),
+	)
+
+	body.WriteString("**Diagnostics**\n\n")
+	for _, d := range diagnostics {
+		body.WriteString(fmt.Sprintf("- %s: %s\n", d.key, redactor.String(d.value)))
+	}
+
+	return body.String()
+}
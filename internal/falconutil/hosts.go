@@ -0,0 +1,105 @@
+package falconutil
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/crowdstrike/gofalcon/falcon/client"
+	"github.com/crowdstrike/gofalcon/falcon/client/hosts"
+	"github.com/crowdstrike/gofalcon/falcon/models"
+)
+
+// ErrHostsScope is returned when the api client can not read hosts.
+var ErrHostsScope = errors.New("api client is missing the Hosts (Read) scope")
+
+const gcpHostsFilter = "service_provider:'GCP'"
+
+// hostsPageSize is the maximum number of hosts returned by a query page or a details request.
+const hostsPageSize = 5000
+
+// Host is a Falcon host running in GCP.
+type Host struct {
+	AID      string
+	Hostname string
+	// InstanceID is the id of the compute instance the host runs on.
+	InstanceID string
+	// Project is the GCP project reported by the sensor.
+	Project  string
+	Zone     string
+	LastSeen time.Time
+}
+
+// GCPHosts lists the hosts whose sensors report running in GCP.
+func GCPHosts(client *client.CrowdStrikeAPISpecification) ([]Host, error) {
+	var aids []string
+	filter := gcpHostsFilter
+	var limit int64 = hostsPageSize
+	var offset *string
+
+	for {
+		resp, err := client.Hosts.QueryDevicesByFilterScroll(&hosts.QueryDevicesByFilterScrollParams{
+			Context: context.Background(),
+			Filter:  &filter,
+			Limit:   &limit,
+			Offset:  offset,
+		})
+
+		var forbidden *hosts.QueryDevicesByFilterScrollForbidden
+		if errors.As(err, &forbidden) {
+			return nil, ErrHostsScope
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		aids = append(aids, resp.Payload.Resources...)
+
+		meta := resp.Payload.Meta
+		if len(resp.Payload.Resources) == 0 || meta == nil || meta.Pagination == nil ||
+			meta.Pagination.Offset == nil || *meta.Pagination.Offset == "" ||
+			(meta.Pagination.Total != nil && int64(len(aids)) >= *meta.Pagination.Total) {
+			break
+		}
+		offset = meta.Pagination.Offset
+	}
+
+	var result []Host
+	for start := 0; start < len(aids); start += hostsPageSize {
+		end := min(start+hostsPageSize, len(aids))
+
+		resp, err := client.Hosts.PostDeviceDetailsV2(&hosts.PostDeviceDetailsV2Params{
+			Context: context.Background(),
+			Body:    &models.MsaIdsRequest{Ids: aids[start:end]},
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, d := range resp.Payload.Resources {
+			result = append(result, newHost(d))
+		}
+	}
+
+	return result, nil
+}
+
+func newHost(d *models.DeviceapiDeviceSwagger) Host {
+	h := Host{
+		Hostname:   d.Hostname,
+		InstanceID: d.InstanceID,
+		Project:    d.ServiceProviderAccountID,
+		Zone:       d.ZoneGroup,
+	}
+
+	if d.DeviceID != nil {
+		h.AID = *d.DeviceID
+	}
+
+	if t, err := time.Parse(time.RFC3339, d.LastSeen); err == nil {
+		h.LastSeen = t
+	}
+
+	return h
+}
@@ -0,0 +1,42 @@
+package falconutil
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/crowdstrike/gofalcon/falcon/client"
+	"github.com/crowdstrike/gofalcon/falcon/client/falcon_container"
+)
+
+// ErrRegistryScope is returned when the api client can not read the CrowdStrike registry credentials.
+var ErrRegistryScope = errors.New(
+	"api client is missing the Falcon Images Download (Read) scope required to pull the node sensor image",
+)
+
+// RegistryCredentials returns the username and token that pull Falcon images from the CrowdStrike
+// registry. The username is fc- followed by the lowercase CID without its checksum.
+func RegistryCredentials(client *client.CrowdStrikeAPISpecification, cid string) (string, string, error) {
+	resp, err := client.FalconContainer.GetCredentials(
+		&falcon_container.GetCredentialsParams{
+			Context: context.Background(),
+		},
+	)
+
+	var forbidden *falcon_container.GetCredentialsForbidden
+	if errors.As(err, &forbidden) {
+		return "", "", ErrRegistryScope
+	}
+
+	if err != nil {
+		return "", "", err
+	}
+
+	if len(resp.Payload.Resources) == 0 || resp.Payload.Resources[0].Token == nil {
+		return "", "", fmt.Errorf("unexpected payload response. No registry credentials found: %v", resp.Payload)
+	}
+
+	username := "fc-" + strings.ToLower(strings.Split(cid, "-")[0])
+	return username, *resp.Payload.Resources[0].Token, nil
+}
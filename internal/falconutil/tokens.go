@@ -0,0 +1,69 @@
+package falconutil
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/crowdstrike/gofalcon/falcon/client"
+	"github.com/crowdstrike/gofalcon/falcon/client/sensor_update_policies"
+	"github.com/crowdstrike/gofalcon/falcon/models"
+)
+
+// BulkMaintenanceDeviceID is the device id that reveals the bulk maintenance token, which is
+// accepted by every host in the CID.
+const BulkMaintenanceDeviceID = "MAINTENANCE"
+
+// ErrMaintenanceTokenScope is returned when the api client can not reveal maintenance tokens.
+var ErrMaintenanceTokenScope = errors.New(
+	"api client is missing the Sensor Update Policies (Write) scope required to reveal maintenance tokens",
+)
+
+const maintenanceTokenAuditMessage = "Revealed by cs-policy"
+
+// MaintenanceToken reveals the bulk maintenance token.
+func MaintenanceToken(client *client.CrowdStrikeAPISpecification) (string, error) {
+	return revealMaintenanceToken(client, BulkMaintenanceDeviceID)
+}
+
+// MaintenanceTokens reveals the maintenance token of each host, keyed by AID.
+func MaintenanceTokens(client *client.CrowdStrikeAPISpecification, aids []string) (map[string]string, error) {
+	tokens := map[string]string{}
+
+	for _, aid := range aids {
+		token, err := revealMaintenanceToken(client, aid)
+		if err != nil {
+			return nil, fmt.Errorf("unable to reveal the maintenance token of host %s: %w", aid, err)
+		}
+		tokens[aid] = token
+	}
+
+	return tokens, nil
+}
+
+func revealMaintenanceToken(client *client.CrowdStrikeAPISpecification, deviceID string) (string, error) {
+	resp, err := client.SensorUpdatePolicies.RevealUninstallToken(
+		&sensor_update_policies.RevealUninstallTokenParams{
+			Context: context.Background(),
+			Body: &models.UninstallTokenRevealUninstallTokenReqV1{
+				AuditMessage: maintenanceTokenAuditMessage,
+				DeviceID:     &deviceID,
+			},
+		},
+	)
+
+	var forbidden *sensor_update_policies.RevealUninstallTokenForbidden
+	if errors.As(err, &forbidden) {
+		return "", ErrMaintenanceTokenScope
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	if len(resp.Payload.Resources) == 0 || resp.Payload.Resources[0].UninstallToken == nil {
+		return "", fmt.Errorf("unexpected payload response. No resources found: %v", resp.Payload.Errors)
+	}
+
+	return *resp.Payload.Resources[0].UninstallToken, nil
+}
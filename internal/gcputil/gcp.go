@@ -0,0 +1,188 @@
+// Package gcputil wraps the GCP APIs used to inspect projects, zones and permissions.
+package gcputil
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os/exec"
+	"strings"
+
+	"cloud.google.com/go/storage"
+	"github.com/crowdstrike/gcp-os-policy/internal/logging"
+	"google.golang.org/api/cloudresourcemanager/v1"
+	"google.golang.org/api/compute/v1"
+	"google.golang.org/api/option"
+	"google.golang.org/api/osconfig/v1"
+	"google.golang.org/api/serviceusage/v1"
+	htransport "google.golang.org/api/transport/http"
+)
+
+const cloudPlatformScope = "https://www.googleapis.com/auth/cloud-platform"
+
+// Client holds the GCP API services used by cs-policy.
+type Client struct {
+	Compute         *compute.Service
+	ServiceUsage    *serviceusage.Service
+	ResourceManager *cloudresourcemanager.Service
+	OsConfig        *osconfig.Service
+}
+
+// httpClient creates an authenticated http client whose requests are debug logged with credentials redacted.
+func httpClient(
+	ctx context.Context,
+	logger *slog.Logger,
+	service string,
+	scopes ...string,
+) (*http.Client, error) {
+	rt, err := htransport.NewTransport(
+		ctx,
+		logging.NewTransport(http.DefaultTransport, logger, service),
+		option.WithScopes(scopes...),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &http.Client{Transport: rt}, nil
+}
+
+// NewStorageClient creates a gcp storage client using application default credentials.
+func NewStorageClient(ctx context.Context, logger *slog.Logger) (*storage.Client, error) {
+	hc, err := httpClient(ctx, logger, "gcs", storage.ScopeFullControl, cloudPlatformScope)
+	if err != nil {
+		return nil, err
+	}
+
+	return storage.NewClient(ctx, option.WithHTTPClient(hc))
+}
+
+// NewClient creates the compute, service usage, resource manager and os config services using application default credentials.
+func NewClient(ctx context.Context, logger *slog.Logger) (*Client, error) {
+	hc, err := httpClient(ctx, logger, "gcp", cloudPlatformScope)
+	if err != nil {
+		return nil, err
+	}
+
+	computeService, err := compute.NewService(ctx, option.WithHTTPClient(hc))
+	if err != nil {
+		return nil, err
+	}
+
+	serviceUsageService, err := serviceusage.NewService(ctx, option.WithHTTPClient(hc))
+	if err != nil {
+		return nil, err
+	}
+
+	resourceManagerService, err := cloudresourcemanager.NewService(ctx, option.WithHTTPClient(hc))
+	if err != nil {
+		return nil, err
+	}
+
+	osConfigService, err := osconfig.NewService(ctx, option.WithHTTPClient(hc))
+	if err != nil {
+		return nil, err
+	}
+
+	return &Client{
+		Compute:         computeService,
+		ServiceUsage:    serviceUsageService,
+		ResourceManager: resourceManagerService,
+		OsConfig:        osConfigService,
+	}, nil
+}
+
+// ActiveProject returns the project currently configured in the gcloud cli.
+func ActiveProject(ctx context.Context) (string, error) {
+	gcloudPath, err := exec.LookPath("gcloud")
+	if err != nil {
+		return "", err
+	}
+
+	bufOut := new(bytes.Buffer)
+	bufErr := new(bytes.Buffer)
+
+	cmd := exec.CommandContext(ctx, gcloudPath, "config", "get-value", "project")
+	cmd.Stdout = bufOut
+	cmd.Stderr = bufErr
+
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("%w: %s", err, bufErr.String())
+	}
+
+	project := strings.TrimSpace(bufOut.String())
+	if project == "" {
+		return "", fmt.Errorf("no project set in the gcloud config")
+	}
+
+	return project, nil
+}
+
+// ServiceEnabled returns true if the service (e.g. osconfig.googleapis.com) is enabled in the project.
+func (c *Client) ServiceEnabled(ctx context.Context, project string, service string) (bool, error) {
+	s, err := c.ServiceUsage.Services.
+		Get(fmt.Sprintf("projects/%s/services/%s", project, service)).
+		Context(ctx).
+		Do()
+	if err != nil {
+		return false, err
+	}
+
+	return s.State == "ENABLED", nil
+}
+
+// ProjectMetadata returns the value of a project wide compute metadata key.
+func (c *Client) ProjectMetadata(ctx context.Context, project string, key string) (string, bool, error) {
+	p, err := c.Compute.Projects.Get(project).Context(ctx).Do()
+	if err != nil {
+		return "", false, err
+	}
+
+	if p.CommonInstanceMetadata == nil {
+		return "", false, nil
+	}
+
+	for _, item := range p.CommonInstanceMetadata.Items {
+		if item.Key == key && item.Value != nil {
+			return *item.Value, true, nil
+		}
+	}
+
+	return "", false, nil
+}
+
+// MissingPermissions returns the permissions the caller lacks on the project.
+func (c *Client) MissingPermissions(
+	ctx context.Context,
+	project string,
+	permissions []string,
+) ([]string, error) {
+	resp, err := c.ResourceManager.Projects.TestIamPermissions(
+		project,
+		&cloudresourcemanager.TestIamPermissionsRequest{Permissions: permissions},
+	).Context(ctx).Do()
+	if err != nil {
+		return nil, err
+	}
+
+	return difference(permissions, resp.Permissions), nil
+}
+
+// difference returns the values of want that are not in have.
+func difference(want []string, have []string) []string {
+	seen := make(map[string]bool, len(have))
+	for _, h := range have {
+		seen[h] = true
+	}
+
+	var missing []string
+	for _, w := range want {
+		if !seen[w] {
+			missing = append(missing, w)
+		}
+	}
+
+	return missing
+}
@@ -0,0 +1,66 @@
+package gcputil
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"google.golang.org/api/compute/v1"
+)
+
+// Instance is a running compute instance.
+type Instance struct {
+	Project string
+	Zone    string
+	Name    string
+	ID      string
+	// Hostname is the custom hostname of the instance, empty when it uses the default hostname.
+	Hostname string
+	Labels   map[string]string
+	// COS is set when the boot disk runs Container-Optimized OS, which OS Policies can not cover.
+	COS bool
+}
+
+// Instances lists the running compute instances in the zone.
+func (c *Client) Instances(ctx context.Context, project string, zone string) ([]Instance, error) {
+	var instances []Instance
+
+	err := c.Compute.Instances.List(project, zone).
+		Filter(`status = "RUNNING"`).
+		Fields("items/name", "items/id", "items/hostname", "items/labels", "items/disks/boot", "items/disks/licenses", "nextPageToken").
+		Pages(ctx, func(page *compute.InstanceList) error {
+			for _, i := range page.Items {
+				instances = append(instances, Instance{
+					Project:  project,
+					Zone:     zone,
+					Name:     i.Name,
+					ID:       strconv.FormatUint(i.Id, 10),
+					Hostname: i.Hostname,
+					Labels:   i.Labels,
+					COS:      bootDiskCOS(i.Disks),
+				})
+			}
+			return nil
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	return instances, nil
+}
+
+// bootDiskCOS returns true when the boot disk carries a Container-Optimized OS license, e.g.
+// https://www.googleapis.com/compute/v1/projects/cos-cloud/global/licenses/cos-pcid.
+func bootDiskCOS(disks []*compute.AttachedDisk) bool {
+	for _, d := range disks {
+		if !d.Boot {
+			continue
+		}
+		for _, license := range d.Licenses {
+			if strings.Contains(license, "/projects/cos-cloud/") {
+				return true
+			}
+		}
+	}
+	return false
+}
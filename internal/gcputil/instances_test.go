@@ -0,0 +1,18 @@
+package gcputil
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/api/compute/v1"
+)
+
+func TestBootDiskCOS(t *testing.T) {
+	cos := "https://www.googleapis.com/compute/v1/projects/cos-cloud/global/licenses/cos-pcid"
+	debian := "https://www.googleapis.com/compute/v1/projects/debian-cloud/global/licenses/debian-12-bookworm"
+
+	assert.True(t, bootDiskCOS([]*compute.AttachedDisk{{Boot: true, Licenses: []string{debian, cos}}}))
+	assert.False(t, bootDiskCOS([]*compute.AttachedDisk{{Boot: true, Licenses: []string{debian}}}))
+	assert.False(t, bootDiskCOS([]*compute.AttachedDisk{{Boot: true}, {Licenses: []string{cos}}}))
+	assert.False(t, bootDiskCOS(nil))
+}
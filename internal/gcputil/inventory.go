@@ -0,0 +1,64 @@
+package gcputil
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"google.golang.org/api/osconfig/v1"
+)
+
+// OSShortNameCOS is the OS Config short name of Container-Optimized OS, the os of GKE nodes.
+const OSShortNameCOS = "cos"
+
+// InstanceOS is the os of a VM as reported by the OS Config agent's inventory.
+type InstanceOS struct {
+	Project string
+	Zone    string
+	// Instance is the instance id of the VM.
+	Instance  string
+	Hostname  string
+	ShortName string
+	Version   string
+}
+
+// InstanceOSes lists the os of every VM in the zone that reports an inventory.
+func (c *Client) InstanceOSes(ctx context.Context, project string, zone string) ([]InstanceOS, error) {
+	parent := fmt.Sprintf("projects/%s/locations/%s/instances/-", project, zone)
+
+	var oses []InstanceOS
+	err := c.OsConfig.Projects.Locations.Instances.Inventories.List(parent).
+		View("BASIC").
+		Fields("inventories/name", "inventories/osInfo", "nextPageToken").
+		Pages(ctx, func(page *osconfig.ListInventoriesResponse) error {
+			for _, i := range page.Inventories {
+				if i.OsInfo == nil {
+					continue
+				}
+				oses = append(oses, InstanceOS{
+					Project:   project,
+					Zone:      zone,
+					Instance:  inventoryInstance(i.Name),
+					Hostname:  i.OsInfo.Hostname,
+					ShortName: i.OsInfo.ShortName,
+					Version:   i.OsInfo.Version,
+				})
+			}
+			return nil
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	return oses, nil
+}
+
+// inventoryInstance returns the instance of an inventory name,
+// projects/{project}/locations/{zone}/instances/{instance}/inventory.
+func inventoryInstance(name string) string {
+	parts := strings.Split(name, "/")
+	if len(parts) < 2 {
+		return name
+	}
+	return parts[len(parts)-2]
+}
@@ -0,0 +1,141 @@
+package gcputil
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"path"
+
+	"google.golang.org/api/osconfig/v1"
+)
+
+const (
+	ComplianceCompliant    = "COMPLIANT"
+	ComplianceNonCompliant = "NON_COMPLIANT"
+	ComplianceUnknown      = "UNKNOWN"
+)
+
+// InstanceCompliance is the compliance state of a single VM for an os policy assignment.
+type InstanceCompliance struct {
+	Instance string
+	State    string
+	// Reason explains an UNKNOWN state, e.g. vm-not-running or no-agent-detected.
+	Reason string
+	// NonCompliantResources are the ids of the os policy resources that are not compliant.
+	NonCompliantResources []string
+	// Output is the enforcement output of the non-compliant exec resources.
+	Output map[string]string
+}
+
+// Compliance counts VMs by compliance state.
+type Compliance struct {
+	Compliant    int
+	NonCompliant int
+	Unknown      int
+}
+
+// Total returns the number of VMs with a report.
+func (c Compliance) Total() int {
+	return c.Compliant + c.NonCompliant + c.Unknown
+}
+
+// Add returns the sum of c and o.
+func (c Compliance) Add(o Compliance) Compliance {
+	return Compliance{
+		Compliant:    c.Compliant + o.Compliant,
+		NonCompliant: c.NonCompliant + o.NonCompliant,
+		Unknown:      c.Unknown + o.Unknown,
+	}
+}
+
+// Summarize counts the reports by compliance state.
+func Summarize(reports []InstanceCompliance) Compliance {
+	var c Compliance
+	for _, r := range reports {
+		switch r.State {
+		case ComplianceCompliant:
+			c.Compliant++
+		case ComplianceNonCompliant:
+			c.NonCompliant++
+		default:
+			c.Unknown++
+		}
+	}
+	return c
+}
+
+// AssignmentReports lists the compliance of every VM targeted by the os policy assignment in the zone.
+func (c *Client) AssignmentReports(
+	ctx context.Context,
+	project string,
+	zone string,
+	assignment string,
+) ([]InstanceCompliance, error) {
+	parent := fmt.Sprintf(
+		"projects/%s/locations/%s/instances/-/osPolicyAssignments/%s",
+		project,
+		zone,
+		assignment,
+	)
+
+	var reports []InstanceCompliance
+	err := c.OsConfig.Projects.Locations.Instances.OsPolicyAssignments.Reports.List(parent).
+		Pages(ctx, func(page *osconfig.ListOSPolicyAssignmentReportsResponse) error {
+			for _, r := range page.OsPolicyAssignmentReports {
+				reports = append(reports, instanceCompliance(r))
+			}
+			return nil
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	return reports, nil
+}
+
+// instanceCompliance reduces the per policy compliance of a report to a single state for the VM.
+func instanceCompliance(r *osconfig.OSPolicyAssignmentReport) InstanceCompliance {
+	ic := InstanceCompliance{
+		Instance: path.Base(r.Instance),
+		State:    ComplianceCompliant,
+		Output:   map[string]string{},
+	}
+
+	if len(r.OsPolicyCompliances) == 0 {
+		ic.State = ComplianceUnknown
+	}
+
+	for _, pc := range r.OsPolicyCompliances {
+		switch pc.ComplianceState {
+		case ComplianceNonCompliant:
+			ic.State = ComplianceNonCompliant
+		case ComplianceCompliant:
+		default:
+			if ic.State == ComplianceCompliant {
+				ic.State = ComplianceUnknown
+				ic.Reason = pc.ComplianceStateReason
+			}
+		}
+
+		for _, rc := range pc.OsPolicyResourceCompliances {
+			if rc.ComplianceState != ComplianceNonCompliant {
+				continue
+			}
+			ic.NonCompliantResources = append(ic.NonCompliantResources, rc.OsPolicyResourceId)
+			if rc.ExecResourceOutput != nil && rc.ExecResourceOutput.EnforcementOutput != "" {
+				ic.Output[rc.OsPolicyResourceId] = decodeOutput(rc.ExecResourceOutput.EnforcementOutput)
+			}
+		}
+	}
+
+	return ic
+}
+
+// decodeOutput decodes the base64 encoded exec output, returning it unchanged if it is not base64.
+func decodeOutput(s string) string {
+	b, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return s
+	}
+	return string(b)
+}
@@ -0,0 +1,207 @@
+package gcputil
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// zoneConcurrency is the number of zones listed at the same time.
+const zoneConcurrency = 10
+
+// TargetProjects combines the projects and the projects in projectsFile into a sorted, de-duplicated list.
+//
+// A single empty project is returned when none are provided so the gcloud cli's active project is used.
+func TargetProjects(projects []string, projectsFile string) ([]string, error) {
+	candidates := append([]string{}, projects...)
+
+	if projectsFile != "" {
+		fromFile, err := readProjectsFile(projectsFile)
+		if err != nil {
+			return nil, err
+		}
+		candidates = append(candidates, fromFile...)
+	}
+
+	seen := map[string]bool{}
+	var result []string
+	for _, p := range candidates {
+		p = strings.TrimSpace(p)
+		if p == "" || seen[p] {
+			continue
+		}
+		seen[p] = true
+		result = append(result, p)
+	}
+
+	if len(result) == 0 {
+		return []string{""}, nil
+	}
+
+	sort.Strings(result)
+	return result, nil
+}
+
+// readProjectsFile reads one project id per line, ignoring blank lines and # comments.
+func readProjectsFile(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var result []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		result = append(result, line)
+	}
+
+	return result, scanner.Err()
+}
+
+// ProjectError is a project whose zones could not be resolved.
+type ProjectError struct {
+	Project string
+	Err     error
+}
+
+func (e *ProjectError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Project, e.Err)
+}
+
+func (e *ProjectError) Unwrap() error {
+	return e.Err
+}
+
+// FailedProjects returns the projects ResolveTargets skipped.
+func FailedProjects(err error) []*ProjectError {
+	var failed []*ProjectError
+
+	joined, ok := err.(interface{ Unwrap() []error })
+	if !ok {
+		return failed
+	}
+
+	for _, e := range joined.Unwrap() {
+		var pe *ProjectError
+		if errors.As(e, &pe) {
+			failed = append(failed, pe)
+		}
+	}
+	return failed
+}
+
+// ResolveTargets resolves the zones to target in each project.
+//
+// An empty project is replaced with the gcloud cli's active project. Projects without any
+// matching zones are included with no zones so the caller can report them. A project whose zones
+// can not be resolved is skipped and returned as a ProjectError, so one project does not stop the
+// others. No targets are returned when every project fails.
+func (c *Client) ResolveTargets(
+	ctx context.Context,
+	projects []string,
+	sel ZoneSelector,
+) (map[string][]string, error) {
+	return resolveTargets(projects, func(project string) (string, []string, error) {
+		if project == "" {
+			var err error
+			project, err = ActiveProject(ctx)
+			if err != nil {
+				return "active gcloud project", nil, fmt.Errorf("unable to determine the active gcloud project: %w", err)
+			}
+		}
+
+		zones, err := c.ResolveZones(ctx, project, sel)
+		return project, zones, err
+	})
+}
+
+func resolveTargets(
+	projects []string,
+	resolve func(project string) (string, []string, error),
+) (map[string][]string, error) {
+	targets := map[string][]string{}
+	var errs []error
+
+	for _, project := range projects {
+		project, zones, err := resolve(project)
+		if err != nil {
+			errs = append(errs, &ProjectError{Project: project, Err: err})
+			continue
+		}
+
+		targets[project] = zones
+	}
+
+	if len(targets) == 0 && len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	return targets, errors.Join(errs...)
+}
+
+// SortedProjects returns the projects of targets sorted alphabetically.
+func SortedProjects(targets map[string][]string) []string {
+	var projects []string
+	for project := range targets {
+		projects = append(projects, project)
+	}
+	sort.Strings(projects)
+	return projects
+}
+
+// ListZones calls list for each zone of targets concurrently and returns the combined results
+// ordered by project and zone. The first error stops the listing and is returned prefixed with
+// the project and zone.
+func ListZones[T any](
+	ctx context.Context,
+	targets map[string][]string,
+	list func(ctx context.Context, project string, zone string) ([]T, error),
+) ([]T, error) {
+	// the slice is sized before any goroutine writes to it, appending would move the results.
+	var n int
+	for _, zones := range targets {
+		n += len(zones)
+	}
+	zoneResults := make([][]T, n)
+
+	eg, egCtx := errgroup.WithContext(ctx)
+	eg.SetLimit(zoneConcurrency)
+
+	var i int
+	for _, project := range SortedProjects(targets) {
+		for _, z := range targets[project] {
+			slot := i
+			i++
+
+			eg.Go(func() error {
+				results, err := list(egCtx, project, z)
+				if err != nil {
+					return fmt.Errorf("%s/%s: %w", project, z, err)
+				}
+				zoneResults[slot] = results
+				return nil
+			})
+		}
+	}
+
+	if err := eg.Wait(); err != nil {
+		return nil, err
+	}
+
+	var results []T
+	for _, zr := range zoneResults {
+		results = append(results, zr...)
+	}
+	return results, nil
+}
@@ -0,0 +1,69 @@
+package gcputil
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveTargets(t *testing.T) {
+	errDenied := errors.New("permission denied")
+
+	resolve := func(project string) (string, []string, error) {
+		if project == "denied" {
+			return project, nil, errDenied
+		}
+		return project, []string{project + "-zone"}, nil
+	}
+
+	t.Run("failed projects are skipped", func(t *testing.T) {
+		targets, err := resolveTargets([]string{"a", "denied", "b"}, resolve)
+		require.Error(t, err)
+		assert.Equal(t, map[string][]string{"a": {"a-zone"}, "b": {"b-zone"}}, targets)
+
+		failed := FailedProjects(err)
+		require.Len(t, failed, 1)
+		assert.Equal(t, "denied", failed[0].Project)
+		assert.ErrorIs(t, failed[0], errDenied)
+	})
+
+	t.Run("every project failed", func(t *testing.T) {
+		targets, err := resolveTargets([]string{"denied"}, resolve)
+		assert.ErrorIs(t, err, errDenied)
+		assert.Nil(t, targets)
+	})
+
+	t.Run("no failures", func(t *testing.T) {
+		targets, err := resolveTargets([]string{"a"}, resolve)
+		assert.NoError(t, err)
+		assert.Empty(t, FailedProjects(err))
+		assert.Len(t, targets, 1)
+	})
+}
+
+func TestListZones(t *testing.T) {
+	targets := map[string][]string{
+		"b": {"b-zone1", "b-zone2"},
+		"a": {"a-zone"},
+		"c": nil,
+	}
+
+	got, err := ListZones(context.Background(), targets, func(_ context.Context, project, zone string) ([]string, error) {
+		return []string{project + "/" + zone}, nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a/a-zone", "b/b-zone1", "b/b-zone2"}, got)
+
+	errDenied := errors.New("permission denied")
+	_, err = ListZones(context.Background(), targets, func(_ context.Context, project, zone string) ([]string, error) {
+		if zone == "b-zone2" {
+			return nil, errDenied
+		}
+		return nil, nil
+	})
+	assert.ErrorIs(t, err, errDenied)
+	assert.ErrorContains(t, err, "b/b-zone2")
+}
@@ -0,0 +1,165 @@
+package gcputil
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"google.golang.org/api/compute/v1"
+)
+
+// Zone is a compute zone available to a project.
+type Zone struct {
+	Name   string
+	Region string
+}
+
+// ZoneSelector describes which zones to target in a project.
+type ZoneSelector struct {
+	// Zones are explicit zone names.
+	Zones []string
+	// Regions are expanded to every zone in the region.
+	Regions []string
+	// AllZones selects every zone available to the project.
+	AllZones bool
+	// WithInstances limits the selection to zones that contain compute instances.
+	// When no other selection is made every zone with instances is selected.
+	WithInstances bool
+}
+
+// Zones returns the zones available to the project that are UP.
+func (c *Client) Zones(ctx context.Context, project string) ([]string, error) {
+	zones, err := c.listZones(ctx, project)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, z := range zones {
+		names = append(names, z.Name)
+	}
+
+	return names, nil
+}
+
+// ZonesWithInstances returns the zones in the project that contain at least one compute instance.
+func (c *Client) ZonesWithInstances(ctx context.Context, project string) (map[string]bool, error) {
+	result := map[string]bool{}
+
+	err := c.Compute.Instances.AggregatedList(project).
+		ReturnPartialSuccess(true).
+		Fields("items/*/instances/name", "nextPageToken").
+		Pages(ctx, func(page *compute.InstanceAggregatedList) error {
+			for scope, list := range page.Items {
+				if len(list.Instances) > 0 {
+					result[path.Base(scope)] = true
+				}
+			}
+			return nil
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// ResolveZones validates and expands the selector against the zones available to the project.
+func (c *Client) ResolveZones(ctx context.Context, project string, sel ZoneSelector) ([]string, error) {
+	available, err := c.listZones(ctx, project)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list zones: %w", err)
+	}
+
+	var withInstances map[string]bool
+	if sel.WithInstances {
+		withInstances, err = c.ZonesWithInstances(ctx, project)
+		if err != nil {
+			return nil, fmt.Errorf("unable to list instances: %w", err)
+		}
+	}
+
+	return selectZones(available, withInstances, sel)
+}
+
+func (c *Client) listZones(ctx context.Context, project string) ([]Zone, error) {
+	var zones []Zone
+
+	err := c.Compute.Zones.List(project).Pages(ctx, func(page *compute.ZoneList) error {
+		for _, z := range page.Items {
+			if z.Status == "UP" {
+				zones = append(zones, Zone{Name: z.Name, Region: path.Base(z.Region)})
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(zones, func(i, j int) bool {
+		return zones[i].Name < zones[j].Name
+	})
+
+	return zones, nil
+}
+
+// selectZones applies sel to the available zones. withInstances is only consulted when sel.WithInstances is set.
+func selectZones(available []Zone, withInstances map[string]bool, sel ZoneSelector) ([]string, error) {
+	byName := make(map[string]Zone, len(available))
+	byRegion := map[string][]string{}
+	for _, z := range available {
+		byName[z.Name] = z
+		byRegion[z.Region] = append(byRegion[z.Region], z.Name)
+	}
+
+	selected := map[string]bool{}
+
+	var unknown []string
+	for _, z := range sel.Zones {
+		if _, ok := byName[z]; !ok {
+			unknown = append(unknown, z)
+			continue
+		}
+		selected[z] = true
+	}
+
+	if len(unknown) > 0 {
+		return nil, fmt.Errorf("unknown or unavailable zones: %s", strings.Join(unknown, ", "))
+	}
+
+	for _, r := range sel.Regions {
+		zones, ok := byRegion[r]
+		if !ok {
+			unknown = append(unknown, r)
+			continue
+		}
+		for _, z := range zones {
+			selected[z] = true
+		}
+	}
+
+	if len(unknown) > 0 {
+		return nil, fmt.Errorf("unknown or unavailable regions: %s", strings.Join(unknown, ", "))
+	}
+
+	// selecting only by instances starts from every zone
+	if sel.AllZones || (sel.WithInstances && len(selected) == 0) {
+		for _, z := range available {
+			selected[z.Name] = true
+		}
+	}
+
+	var result []string
+	for z := range selected {
+		if sel.WithInstances && !withInstances[z] {
+			continue
+		}
+		result = append(result, z)
+	}
+
+	sort.Strings(result)
+	return result, nil
+}
@@ -0,0 +1,87 @@
+package gcputil
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSelectZones(t *testing.T) {
+	available := []Zone{
+		{Name: "europe-west4-a", Region: "europe-west4"},
+		{Name: "europe-west4-b", Region: "europe-west4"},
+		{Name: "us-central1-a", Region: "us-central1"},
+		{Name: "us-central1-b", Region: "us-central1"},
+		{Name: "us-east1-b", Region: "us-east1"},
+	}
+
+	withInstances := map[string]bool{
+		"europe-west4-b": true,
+		"us-central1-a":  true,
+	}
+
+	tests := []struct {
+		name    string
+		sel     ZoneSelector
+		want    []string
+		wantErr string
+	}{
+		{
+			name: "explicit zones",
+			sel:  ZoneSelector{Zones: []string{"us-east1-b", "us-central1-a"}},
+			want: []string{"us-central1-a", "us-east1-b"},
+		},
+		{
+			name:    "unknown zone",
+			sel:     ZoneSelector{Zones: []string{"us-central1-a", "us-central1-z"}},
+			wantErr: "unknown or unavailable zones: us-central1-z",
+		},
+		{
+			name: "regions expand to zones",
+			sel:  ZoneSelector{Regions: []string{"us-central1", "europe-west4"}},
+			want: []string{"europe-west4-a", "europe-west4-b", "us-central1-a", "us-central1-b"},
+		},
+		{
+			name:    "unknown region",
+			sel:     ZoneSelector{Regions: []string{"mars-north1"}},
+			wantErr: "unknown or unavailable regions: mars-north1",
+		},
+		{
+			name: "zones and regions are merged",
+			sel:  ZoneSelector{Zones: []string{"us-east1-b"}, Regions: []string{"us-central1"}},
+			want: []string{"us-central1-a", "us-central1-b", "us-east1-b"},
+		},
+		{
+			name: "all zones",
+			sel:  ZoneSelector{AllZones: true},
+			want: []string{"europe-west4-a", "europe-west4-b", "us-central1-a", "us-central1-b", "us-east1-b"},
+		},
+		{
+			name: "only zones with instances",
+			sel:  ZoneSelector{WithInstances: true},
+			want: []string{"europe-west4-b", "us-central1-a"},
+		},
+		{
+			name: "regions limited to zones with instances",
+			sel:  ZoneSelector{Regions: []string{"us-central1"}, WithInstances: true},
+			want: []string{"us-central1-a"},
+		},
+		{
+			name: "no zones with instances",
+			sel:  ZoneSelector{Zones: []string{"us-east1-b"}, WithInstances: true},
+			want: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := selectZones(available, withInstances, tt.sel)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
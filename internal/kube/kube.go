@@ -0,0 +1,152 @@
+// Package kube generates the Kubernetes manifests that deploy the Falcon node sensor to GKE nodes
+// running Container-Optimized OS, which the OS Policy can not install packages on.
+package kube
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/crowdstrike/gcp-os-policy/internal/policy"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	FormatDaemonSet = "daemonset"
+	FormatHelm      = "helm"
+)
+
+// Namespace is the namespace the node sensor is deployed to.
+const Namespace = "falcon-system"
+
+const (
+	name       = "falcon-node-sensor"
+	configName = "falcon-node-sensor-config"
+	secretName = "falcon-node-sensor-secret"
+	// falconstore is the sensor's state on the node, it must survive pod restarts.
+	falconstoreDir  = "/opt/CrowdStrike"
+	falconstorePath = falconstoreDir + "/falconstore"
+)
+
+// ParseFormat parses the output format of the node sensor manifest.
+func ParseFormat(s string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", FormatDaemonSet:
+		return FormatDaemonSet, nil
+	case FormatHelm:
+		return FormatHelm, nil
+	}
+
+	return "", fmt.Errorf("invalid format %q: must be one of daemonset, helm", s)
+}
+
+// Sensor is the node sensor deployed to the nodes.
+type Sensor struct {
+	Cid string
+	// Image is the node sensor image reference, e.g.
+	// us-docker.pkg.dev/my-project/falcon/falcon-sensor:7.20.0-17306-1.falcon-linux.Release.US-1
+	Image   string
+	Options policy.SensorOptions
+}
+
+// Validate checks the sensor can be deployed to Container-Optimized OS nodes.
+func (s Sensor) Validate() error {
+	var errs []error
+
+	if s.Cid == "" {
+		errs = append(errs, errors.New("a cid is required"))
+	}
+
+	if s.Image == "" {
+		errs = append(errs, errors.New("a node sensor image is required"))
+	} else if repository, tag, digest := splitImage(s.Image); repository == "" || tag == "" && digest == "" {
+		errs = append(errs, fmt.Errorf("invalid image %q: must include a tag or digest", s.Image))
+	}
+
+	if s.Options.ProvisioningTokenSecret != "" {
+		errs = append(errs, errors.New("provisioning token secrets are not supported by the node sensor, use a provisioning token"))
+	}
+
+	// Container-Optimized OS does not allow the sensor's kernel modules to be loaded.
+	if s.Options.Backend == "kernel" {
+		errs = append(errs, errors.New("the kernel backend is not supported on Container-Optimized OS, use bpf"))
+	}
+
+	if err := s.Options.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+
+	return errors.Join(errs...)
+}
+
+// options returns the falconctl options of the node sensor keyed by the FALCONCTL_OPT_ environment
+// variable suffix the node sensor reads them from.
+func (s Sensor) options() map[string]string {
+	o := s.Options
+	opts := map[string]string{
+		"CID":     s.Cid,
+		"BACKEND": "bpf",
+	}
+
+	if len(o.Tags) > 0 {
+		opts["TAGS"] = strings.Join(o.Tags, ",")
+	}
+
+	if o.ProxyHost != "" {
+		opts["APD"] = "false"
+		opts["APH"] = o.ProxyHost
+	}
+
+	if o.ProxyPort > 0 {
+		opts["APP"] = strconv.Itoa(o.ProxyPort)
+	}
+
+	if o.DisableProxy {
+		opts["APD"] = "true"
+	}
+
+	if o.ProvisioningToken != "" {
+		opts["PROVISIONING_TOKEN"] = o.ProvisioningToken
+	}
+
+	if o.Billing != "" {
+		opts["BILLING"] = o.Billing
+	}
+
+	return opts
+}
+
+// Write writes the node sensor manifest in the given format.
+func (s Sensor) Write(wr io.Writer, format string) error {
+	if format == FormatHelm {
+		return encode(wr, s.helmValues())
+	}
+	return encode(wr, s.manifests()...)
+}
+
+// encode writes the documents as a multi document yaml stream.
+func encode(wr io.Writer, docs ...any) error {
+	enc := yaml.NewEncoder(wr)
+	enc.SetIndent(2)
+
+	for _, doc := range docs {
+		if err := enc.Encode(doc); err != nil {
+			return err
+		}
+	}
+	return enc.Close()
+}
+
+// splitImage splits an image reference into its repository, tag and digest.
+func splitImage(image string) (repository string, tag string, digest string) {
+	repository, digest, _ = strings.Cut(image, "@")
+
+	// a colon after the last slash separates the tag, earlier colons are a registry port.
+	if i := strings.LastIndex(repository, ":"); i > strings.LastIndex(repository, "/") {
+		repository, tag = repository[:i], repository[i+1:]
+	}
+
+	return repository, tag, digest
+}
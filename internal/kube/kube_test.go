@@ -0,0 +1,185 @@
+package kube
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/crowdstrike/gcp-os-policy/internal/policy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+const testImage = "us-docker.pkg.dev/example/falcon/falcon-sensor:7.20.0-17306-1.falcon-linux.Release.US-1"
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		sensor  Sensor
+		wantErr string
+	}{
+		{name: "valid", sensor: Sensor{Cid: "cid", Image: testImage}},
+		{name: "digest", sensor: Sensor{Cid: "cid", Image: "localhost:5000/falcon-sensor@sha256:abc"}},
+		{name: "missing image", sensor: Sensor{Cid: "cid"}, wantErr: "a node sensor image is required"},
+		{name: "missing tag", sensor: Sensor{Cid: "cid", Image: "localhost:5000/falcon-sensor"}, wantErr: "must include a tag or digest"},
+		{
+			name:    "kernel backend",
+			sensor:  Sensor{Cid: "cid", Image: testImage, Options: policy.SensorOptions{Backend: "kernel"}},
+			wantErr: "the kernel backend is not supported",
+		},
+		{
+			name:    "provisioning token secret",
+			sensor:  Sensor{Cid: "cid", Image: testImage, Options: policy.SensorOptions{ProvisioningTokenSecret: "projects/p/secrets/token"}},
+			wantErr: "provisioning token secrets are not supported",
+		},
+		{
+			name:    "invalid tags",
+			sensor:  Sensor{Cid: "cid", Image: testImage, Options: policy.SensorOptions{Tags: []string{"a b"}}},
+			wantErr: `invalid tag "a b"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.sensor.Validate()
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestWriteDaemonSet(t *testing.T) {
+	s := Sensor{
+		Cid:   "0123456789ABCDEF0123456789ABCDEF-12",
+		Image: testImage,
+		Options: policy.SensorOptions{
+			Tags:      []string{"gke", "prod"},
+			ProxyHost: "proxy.example.com",
+			ProxyPort: 8080,
+			Backend:   "auto",
+		},
+	}
+
+	docs, manifest := writeDaemonSet(t, s)
+
+	require.Len(t, docs, 3)
+	assert.Equal(t, "Namespace", docs[0]["kind"])
+	assert.Equal(t, "ConfigMap", docs[1]["kind"])
+	assert.Equal(t, map[string]any{
+		"FALCONCTL_OPT_CID":     "0123456789ABCDEF0123456789ABCDEF-12",
+		"FALCONCTL_OPT_BACKEND": "bpf",
+		"FALCONCTL_OPT_TAGS":    "gke,prod",
+		"FALCONCTL_OPT_APD":     "false",
+		"FALCONCTL_OPT_APH":     "proxy.example.com",
+		"FALCONCTL_OPT_APP":     "8080",
+	}, docs[1]["data"])
+
+	assert.Equal(t, "DaemonSet", docs[2]["kind"])
+	assert.Contains(t, manifest, "image: "+testImage)
+	assert.Equal(t, Namespace, docs[2]["metadata"].(map[string]any)["namespace"])
+	assert.Equal(t, map[string]any{
+		"kubernetes.io/os":                     "linux",
+		"cloud.google.com/gke-os-distribution": "cos",
+	}, podSpecOf(docs[2])["nodeSelector"])
+	assert.NotContains(t, manifest, "secretRef")
+
+	// the provisioning token is kept out of the config map.
+	s.Options.ProvisioningToken = "token"
+	docs, _ = writeDaemonSet(t, s)
+
+	require.Len(t, docs, 4)
+	assert.NotContains(t, docs[1]["data"], "FALCONCTL_OPT_PROVISIONING_TOKEN")
+	assert.Equal(t, "Secret", docs[2]["kind"])
+	assert.Equal(t, map[string]any{"FALCONCTL_OPT_PROVISIONING_TOKEN": "token"}, docs[2]["stringData"])
+	assert.Equal(t, "DaemonSet", docs[3]["kind"])
+
+	containers := podSpecOf(docs[3])["containers"].([]any)
+	assert.Equal(t, []any{
+		map[string]any{"configMapRef": map[string]any{"name": configName}},
+		map[string]any{"secretRef": map[string]any{"name": secretName}},
+	}, containers[0].(map[string]any)["envFrom"])
+}
+
+// writeDaemonSet writes the DaemonSet manifest of s and returns its decoded documents.
+func writeDaemonSet(t *testing.T, s Sensor) ([]map[string]any, string) {
+	var buf bytes.Buffer
+	require.NoError(t, s.Write(&buf, FormatDaemonSet))
+	manifest := buf.String()
+
+	var docs []map[string]any
+	dec := yaml.NewDecoder(&buf)
+	for {
+		var doc map[string]any
+		err := dec.Decode(&doc)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		require.NoError(t, err)
+		docs = append(docs, doc)
+	}
+	return docs, manifest
+}
+
+func podSpecOf(daemonSet map[string]any) map[string]any {
+	spec := daemonSet["spec"].(map[string]any)
+	return spec["template"].(map[string]any)["spec"].(map[string]any)
+}
+
+func TestWriteHelmValues(t *testing.T) {
+	s := Sensor{
+		Cid:     "cid",
+		Image:   "localhost:5000/falcon-sensor@sha256:abc",
+		Options: policy.SensorOptions{DisableProxy: true, ProvisioningToken: "token"},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, s.Write(&buf, FormatHelm))
+
+	assert.Equal(t, `node:
+  enabled: true
+  backend: bpf
+  image:
+    repository: localhost:5000/falcon-sensor
+    digest: sha256:abc
+  daemonset:
+    nodeAffinity:
+      requiredDuringSchedulingIgnoredDuringExecution:
+        nodeSelectorTerms:
+          - matchExpressions:
+              - key: kubernetes.io/os
+                operator: In
+                values:
+                  - linux
+              - key: cloud.google.com/gke-os-distribution
+                operator: In
+                values:
+                  - cos
+falcon:
+  apd: "true"
+  cid: cid
+  provisioning_token: token
+`, buf.String())
+}
+
+func TestSplitImage(t *testing.T) {
+	tests := []struct {
+		image                   string
+		repository, tag, digest string
+	}{
+		{image: "falcon-sensor:7.20", repository: "falcon-sensor", tag: "7.20"},
+		{image: "localhost:5000/falcon-sensor", repository: "localhost:5000/falcon-sensor"},
+		{image: "localhost:5000/falcon-sensor:7.20@sha256:abc", repository: "localhost:5000/falcon-sensor", tag: "7.20", digest: "sha256:abc"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.image, func(t *testing.T) {
+			repository, tag, digest := splitImage(tt.image)
+			assert.Equal(t, []string{tt.repository, tt.tag, tt.digest}, []string{repository, tag, digest})
+		})
+	}
+}
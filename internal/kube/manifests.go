@@ -0,0 +1,301 @@
+package kube
+
+import "strings"
+
+type objectMeta struct {
+	Name      string            `yaml:"name"`
+	Namespace string            `yaml:"namespace,omitempty"`
+	Labels    map[string]string `yaml:"labels,omitempty"`
+}
+
+type namespace struct {
+	APIVersion string     `yaml:"apiVersion"`
+	Kind       string     `yaml:"kind"`
+	Metadata   objectMeta `yaml:"metadata"`
+}
+
+type configMap struct {
+	APIVersion string            `yaml:"apiVersion"`
+	Kind       string            `yaml:"kind"`
+	Metadata   objectMeta        `yaml:"metadata"`
+	Data       map[string]string `yaml:"data"`
+}
+
+type secret struct {
+	APIVersion string            `yaml:"apiVersion"`
+	Kind       string            `yaml:"kind"`
+	Metadata   objectMeta        `yaml:"metadata"`
+	Type       string            `yaml:"type"`
+	StringData map[string]string `yaml:"stringData"`
+}
+
+type daemonSet struct {
+	APIVersion string        `yaml:"apiVersion"`
+	Kind       string        `yaml:"kind"`
+	Metadata   objectMeta    `yaml:"metadata"`
+	Spec       daemonSetSpec `yaml:"spec"`
+}
+
+type daemonSetSpec struct {
+	Selector       labelSelector     `yaml:"selector"`
+	UpdateStrategy map[string]string `yaml:"updateStrategy"`
+	Template       podTemplateSpec   `yaml:"template"`
+}
+
+type labelSelector struct {
+	MatchLabels map[string]string `yaml:"matchLabels"`
+}
+
+type podTemplateSpec struct {
+	Metadata objectMeta `yaml:"metadata"`
+	Spec     podSpec    `yaml:"spec"`
+}
+
+type podSpec struct {
+	NodeSelector                  map[string]string `yaml:"nodeSelector"`
+	Tolerations                   []toleration      `yaml:"tolerations"`
+	HostPID                       bool              `yaml:"hostPID"`
+	HostIPC                       bool              `yaml:"hostIPC"`
+	HostNetwork                   bool              `yaml:"hostNetwork"`
+	DNSPolicy                     string            `yaml:"dnsPolicy"`
+	TerminationGracePeriodSeconds int               `yaml:"terminationGracePeriodSeconds"`
+	InitContainers                []container       `yaml:"initContainers"`
+	Containers                    []container       `yaml:"containers"`
+	Volumes                       []volume          `yaml:"volumes"`
+}
+
+type toleration struct {
+	Operator string `yaml:"operator"`
+}
+
+type container struct {
+	Name            string          `yaml:"name"`
+	Image           string          `yaml:"image"`
+	Command         []string        `yaml:"command,omitempty"`
+	EnvFrom         []envFrom       `yaml:"envFrom,omitempty"`
+	SecurityContext securityContext `yaml:"securityContext"`
+	VolumeMounts    []volumeMount   `yaml:"volumeMounts"`
+}
+
+type envFrom struct {
+	ConfigMapRef *objectMeta `yaml:"configMapRef,omitempty"`
+	SecretRef    *objectMeta `yaml:"secretRef,omitempty"`
+}
+
+type securityContext struct {
+	Privileged               bool `yaml:"privileged"`
+	RunAsUser                int  `yaml:"runAsUser"`
+	ReadOnlyRootFilesystem   bool `yaml:"readOnlyRootFilesystem"`
+	AllowPrivilegeEscalation bool `yaml:"allowPrivilegeEscalation"`
+}
+
+type volumeMount struct {
+	Name      string `yaml:"name"`
+	MountPath string `yaml:"mountPath"`
+}
+
+type volume struct {
+	Name     string   `yaml:"name"`
+	HostPath hostPath `yaml:"hostPath"`
+}
+
+type hostPath struct {
+	Path string `yaml:"path"`
+	Type string `yaml:"type,omitempty"`
+}
+
+// cosNodeLabels select the COS nodes, the OS Policy covers the nodes running other images.
+var cosNodeLabels = [][2]string{
+	{"kubernetes.io/os", "linux"},
+	{"cloud.google.com/gke-os-distribution", "cos"},
+}
+
+func cosNodeSelector() map[string]string {
+	selector := map[string]string{}
+	for _, l := range cosNodeLabels {
+		selector[l[0]] = l[1]
+	}
+	return selector
+}
+
+// initFalconstoreScript makes sure the falconstore is a file on the node, the hostPath mount
+// creates a directory when it does not exist.
+const initFalconstoreScript = `if [ -d ` + falconstorePath + ` ]; then
+  rm -rf ` + falconstorePath + `
+fi
+touch ` + falconstorePath + `
+`
+
+// manifests returns the namespace, the config map holding the falconctl options, the secret
+// holding the provisioning token and the DaemonSet that runs the node sensor on every COS node.
+func (s Sensor) manifests() []any {
+	labels := map[string]string{"app.kubernetes.io/name": name}
+	privileged := securityContext{Privileged: true, AllowPrivilegeEscalation: true}
+
+	opts := s.options()
+	secretData := map[string]string{}
+	if token, ok := opts["PROVISIONING_TOKEN"]; ok {
+		secretData["FALCONCTL_OPT_PROVISIONING_TOKEN"] = token
+		delete(opts, "PROVISIONING_TOKEN")
+	}
+
+	data := map[string]string{}
+	for key, value := range opts {
+		data["FALCONCTL_OPT_"+key] = value
+	}
+
+	env := []envFrom{{ConfigMapRef: &objectMeta{Name: configName}}}
+	var secrets []any
+	if len(secretData) > 0 {
+		env = append(env, envFrom{SecretRef: &objectMeta{Name: secretName}})
+		secrets = append(secrets, secret{
+			APIVersion: "v1",
+			Kind:       "Secret",
+			Metadata:   objectMeta{Name: secretName, Namespace: Namespace, Labels: labels},
+			Type:       "Opaque",
+			StringData: secretData,
+		})
+	}
+
+	manifests := []any{
+		namespace{
+			APIVersion: "v1",
+			Kind:       "Namespace",
+			Metadata: objectMeta{
+				Name: Namespace,
+				// the node sensor runs privileged with the host's namespaces.
+				Labels: map[string]string{"pod-security.kubernetes.io/enforce": "privileged"},
+			},
+		},
+		configMap{
+			APIVersion: "v1",
+			Kind:       "ConfigMap",
+			Metadata:   objectMeta{Name: configName, Namespace: Namespace, Labels: labels},
+			Data:       data,
+		},
+	}
+	manifests = append(manifests, secrets...)
+
+	return append(manifests,
+		daemonSet{
+			APIVersion: "apps/v1",
+			Kind:       "DaemonSet",
+			Metadata:   objectMeta{Name: name, Namespace: Namespace, Labels: labels},
+			Spec: daemonSetSpec{
+				Selector:       labelSelector{MatchLabels: labels},
+				UpdateStrategy: map[string]string{"type": "RollingUpdate"},
+				Template: podTemplateSpec{
+					Metadata: objectMeta{Name: name, Labels: labels},
+					Spec: podSpec{
+						NodeSelector: cosNodeSelector(),
+						// run on every node, including tainted system and gpu node pools.
+						Tolerations:                   []toleration{{Operator: "Exists"}},
+						HostPID:                       true,
+						HostIPC:                       true,
+						HostNetwork:                   true,
+						DNSPolicy:                     "ClusterFirstWithHostNet",
+						TerminationGracePeriodSeconds: 60,
+						InitContainers: []container{
+							{
+								Name:            "init-falconstore",
+								Image:           s.Image,
+								Command:         []string{"/bin/bash", "-c", initFalconstoreScript},
+								SecurityContext: privileged,
+								VolumeMounts:    []volumeMount{{Name: "falconstore-dir", MountPath: falconstoreDir}},
+							},
+						},
+						Containers: []container{
+							{
+								Name:            name,
+								Image:           s.Image,
+								EnvFrom:         env,
+								SecurityContext: privileged,
+								VolumeMounts:    []volumeMount{{Name: "falconstore", MountPath: falconstorePath}},
+							},
+						},
+						Volumes: []volume{
+							{Name: "falconstore", HostPath: hostPath{Path: falconstorePath}},
+							{Name: "falconstore-dir", HostPath: hostPath{Path: falconstoreDir, Type: "DirectoryOrCreate"}},
+						},
+					},
+				},
+			},
+		},
+	)
+}
+
+// helmValues are the values of the CrowdStrike falcon-sensor Helm chart
+// (https://github.com/CrowdStrike/falcon-helm) that deploy the node sensor.
+type helmValues struct {
+	Node   helmNode          `yaml:"node"`
+	Falcon map[string]string `yaml:"falcon"`
+}
+
+type helmNode struct {
+	Enabled   bool          `yaml:"enabled"`
+	Backend   string        `yaml:"backend"`
+	Image     helmImage     `yaml:"image"`
+	DaemonSet helmDaemonSet `yaml:"daemonset"`
+}
+
+// helmDaemonSet replaces the chart's default node affinity, which runs on every linux node.
+type helmDaemonSet struct {
+	NodeAffinity nodeAffinity `yaml:"nodeAffinity"`
+}
+
+type nodeAffinity struct {
+	Required nodeSelector `yaml:"requiredDuringSchedulingIgnoredDuringExecution"`
+}
+
+type nodeSelector struct {
+	NodeSelectorTerms []nodeSelectorTerm `yaml:"nodeSelectorTerms"`
+}
+
+type nodeSelectorTerm struct {
+	MatchExpressions []nodeSelectorRequirement `yaml:"matchExpressions"`
+}
+
+type nodeSelectorRequirement struct {
+	Key      string   `yaml:"key"`
+	Operator string   `yaml:"operator"`
+	Values   []string `yaml:"values"`
+}
+
+type helmImage struct {
+	Repository string `yaml:"repository"`
+	Tag        string `yaml:"tag,omitempty"`
+	Digest     string `yaml:"digest,omitempty"`
+}
+
+func (s Sensor) helmValues() helmValues {
+	opts := s.options()
+	backend := opts["BACKEND"]
+	delete(opts, "BACKEND")
+
+	falcon := map[string]string{}
+	for key, value := range opts {
+		falcon[strings.ToLower(key)] = value
+	}
+
+	repository, tag, digest := splitImage(s.Image)
+
+	var cos nodeSelectorTerm
+	for _, l := range cosNodeLabels {
+		cos.MatchExpressions = append(
+			cos.MatchExpressions,
+			nodeSelectorRequirement{Key: l[0], Operator: "In", Values: []string{l[1]}},
+		)
+	}
+
+	return helmValues{
+		Node: helmNode{
+			Enabled: true,
+			Backend: backend,
+			Image:   helmImage{Repository: repository, Tag: tag, Digest: digest},
+			DaemonSet: helmDaemonSet{
+				NodeAffinity: nodeAffinity{Required: nodeSelector{NodeSelectorTerms: []nodeSelectorTerm{cos}}},
+			},
+		},
+		Falcon: falcon,
+	}
+}
@@ -0,0 +1,93 @@
+// Package logging configures the structured logger used across cs-policy.
+package logging
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+const (
+	// Stderr is the log file that writes logs to stderr.
+	Stderr = "-"
+	// DebugFile is the log file debug logs are written to when no log file is given, so they do
+	// not interleave with the progress bars drawn on the terminal.
+	DebugFile = "cs-policy-debug.log"
+)
+
+// Options controls how the logger is built.
+type Options struct {
+	// Level is one of debug, info, warn, error.
+	Level string
+	// Format is one of text or json.
+	Format string
+	// File is an optional path to write logs to. Logs go to stderr when empty or Stderr, except
+	// debug logs which go to DebugFile when empty.
+	File string
+}
+
+type nopCloser struct{}
+
+func (nopCloser) Close() error { return nil }
+
+// New creates a slog.Logger from the given options.
+//
+// The returned io.Closer must be closed once logging is complete so a log file is flushed.
+func New(opts Options) (*slog.Logger, io.Closer, error) {
+	var level slog.Level
+	if opts.Level != "" {
+		if err := level.UnmarshalText([]byte(opts.Level)); err != nil {
+			return nil, nil, fmt.Errorf(
+				"invalid log level %q: must be one of debug, info, warn, error",
+				opts.Level,
+			)
+		}
+	}
+
+	var w io.Writer = os.Stderr
+	var closer io.Closer = nopCloser{}
+
+	file := opts.File
+	if file == "" && level == slog.LevelDebug {
+		file = DebugFile
+	}
+
+	if file != "" && file != Stderr {
+		f, err := os.OpenFile(file, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+		if err != nil {
+			return nil, nil, fmt.Errorf("unable to open log file %s: %w", file, err)
+		}
+		w = f
+		closer = f
+	}
+
+	handlerOpts := &slog.HandlerOptions{Level: level}
+
+	var handler slog.Handler
+	switch strings.ToLower(opts.Format) {
+	case "", FormatText:
+		handler = slog.NewTextHandler(w, handlerOpts)
+	case FormatJSON:
+		handler = slog.NewJSONHandler(w, handlerOpts)
+	default:
+		closer.Close()
+		return nil, nil, fmt.Errorf("invalid log format %q: must be one of text, json", opts.Format)
+	}
+
+	return slog.New(handler), closer, nil
+}
+
+// Or returns l, falling back to slog.Default when l is nil.
+func Or(l *slog.Logger) *slog.Logger {
+	if l == nil {
+		return slog.Default()
+	}
+	return l
+}
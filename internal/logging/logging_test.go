@@ -0,0 +1,39 @@
+package logging
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewLogFile(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     Options
+		wantFile bool
+	}{
+		{name: "warn to stderr", opts: Options{Level: "warn"}},
+		{name: "debug to debug file", opts: Options{Level: "debug"}, wantFile: true},
+		{name: "debug to stderr", opts: Options{Level: "debug", File: Stderr}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wd, err := os.Getwd()
+			require.NoError(t, err)
+			require.NoError(t, os.Chdir(t.TempDir()))
+			t.Cleanup(func() { os.Chdir(wd) })
+
+			logger, closer, err := New(tt.opts)
+			require.NoError(t, err)
+			logger.Debug("message")
+			require.NoError(t, closer.Close())
+
+			_, err = os.Stat(filepath.Join(".", DebugFile))
+			assert.Equal(t, tt.wantFile, err == nil)
+		})
+	}
+}
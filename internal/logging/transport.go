@@ -0,0 +1,140 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const redacted = "REDACTED"
+
+// sensitiveHeaders are never written to the logs.
+var sensitiveHeaders = map[string]bool{
+	"Authorization":                  true,
+	"Proxy-Authorization":            true,
+	"Cookie":                         true,
+	"Set-Cookie":                     true,
+	"X-Goog-Api-Key":                 true,
+	"X-Goog-Iam-Authorization-Token": true,
+}
+
+// sensitiveParams are query parameters that carry credentials.
+var sensitiveParams = []string{
+	"access_token",
+	"client_secret",
+	"key",
+	"token",
+	"upload_id",
+	"x-goog-signature",
+	"x-goog-credential",
+	"x-amz-signature",
+	"x-amz-credential",
+	"signature",
+}
+
+// Transport is a http.RoundTripper that logs requests and responses at debug level
+// with credentials redacted.
+type Transport struct {
+	Base    http.RoundTripper
+	Logger  *slog.Logger
+	Service string
+}
+
+// NewTransport wraps base with request/response debug logging.
+func NewTransport(base http.RoundTripper, logger *slog.Logger, service string) *Transport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	return &Transport{
+		Base:    base,
+		Logger:  Or(logger),
+		Service: service,
+	}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	if !t.Logger.Enabled(ctx, slog.LevelDebug) {
+		return t.Base.RoundTrip(req)
+	}
+
+	start := time.Now()
+	t.Logger.LogAttrs(ctx, slog.LevelDebug, "http request",
+		slog.String("service", t.Service),
+		slog.String("method", req.Method),
+		slog.String("url", RedactURL(req.URL)),
+		slog.Any("headers", RedactHeaders(req.Header)),
+	)
+
+	resp, err := t.Base.RoundTrip(req)
+	if err != nil {
+		t.Logger.LogAttrs(ctx, slog.LevelDebug, "http request failed",
+			slog.String("service", t.Service),
+			slog.String("method", req.Method),
+			slog.String("url", RedactURL(req.URL)),
+			slog.Duration("duration", time.Since(start)),
+			slog.String("error", err.Error()),
+		)
+		return resp, err
+	}
+
+	t.logResponse(ctx, req, resp, time.Since(start))
+	return resp, nil
+}
+
+func (t *Transport) logResponse(
+	ctx context.Context,
+	req *http.Request,
+	resp *http.Response,
+	duration time.Duration,
+) {
+	t.Logger.LogAttrs(ctx, slog.LevelDebug, "http response",
+		slog.String("service", t.Service),
+		slog.String("method", req.Method),
+		slog.String("url", RedactURL(req.URL)),
+		slog.Int("status", resp.StatusCode),
+		slog.Int64("content_length", resp.ContentLength),
+		slog.Duration("duration", duration),
+		slog.Any("headers", RedactHeaders(resp.Header)),
+	)
+}
+
+// RedactHeaders returns a copy of h with credential carrying headers redacted.
+func RedactHeaders(h http.Header) map[string]string {
+	out := make(map[string]string, len(h))
+	for k, v := range h {
+		if sensitiveHeaders[http.CanonicalHeaderKey(k)] {
+			out[k] = redacted
+			continue
+		}
+		out[k] = strings.Join(v, ",")
+	}
+	return out
+}
+
+// RedactURL returns u as a string with credential carrying query parameters redacted.
+func RedactURL(u *url.URL) string {
+	if u == nil {
+		return ""
+	}
+
+	c := *u
+	c.User = nil
+
+	q := c.Query()
+	for k := range q {
+		for _, p := range sensitiveParams {
+			if strings.EqualFold(k, p) {
+				q.Set(k, redacted)
+			}
+		}
+	}
+	c.RawQuery = q.Encode()
+
+	return c.String()
+}
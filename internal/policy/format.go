@@ -0,0 +1,64 @@
+package policy
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	FormatJSON = "json"
+	FormatYAML = "yaml"
+)
+
+// ParseFormat parses the output format of the generated policy.
+func ParseFormat(s string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", FormatJSON:
+		return FormatJSON, nil
+	case FormatYAML, "yml":
+		return FormatYAML, nil
+	}
+
+	return "", fmt.Errorf("invalid format %q: must be one of json, yaml", s)
+}
+
+// writeYAML writes v as yaml with the same structure as its json encoding.
+//
+// v is round tripped through json so the json struct tags are honored, multi-line strings such
+// as exec scripts are written as literal blocks so they can be reviewed and diffed.
+func writeYAML(wr io.Writer, v any) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	// json is valid yaml, decoding into a node keeps the key order of the json document.
+	var doc yaml.Node
+	if err := yaml.Unmarshal(b, &doc); err != nil {
+		return err
+	}
+	blockStyle(&doc)
+
+	enc := yaml.NewEncoder(wr)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// blockStyle replaces the flow style of a json document with block style.
+func blockStyle(n *yaml.Node) {
+	n.Style = 0
+	if n.Kind == yaml.ScalarNode && n.Tag == "!!str" && strings.Contains(n.Value, "\n") {
+		n.Style = yaml.LiteralStyle
+	}
+
+	for _, c := range n.Content {
+		blockStyle(c)
+	}
+}
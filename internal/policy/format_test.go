@@ -0,0 +1,58 @@
+package policy
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestParseFormat(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{input: "", want: FormatJSON},
+		{input: "JSON", want: FormatJSON},
+		{input: "yaml", want: FormatYAML},
+		{input: "yml", want: FormatYAML},
+		{input: "toml", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseFormat(tt.input)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestGeneratePolicy_YAML(t *testing.T) {
+	p := NewPolicy("cid", `--tags="a b"`, "", SensorOptions{}, stagedSensors(), nil, nil)
+
+	var jsonBuf, yamlBuf bytes.Buffer
+	require.NoError(t, p.GeneratePolicy(&jsonBuf, FormatJSON))
+	require.NoError(t, p.GeneratePolicy(&yamlBuf, FormatYAML))
+
+	// the yaml document must be structurally identical to the json document.
+	var fromYAML any
+	require.NoError(t, yaml.Unmarshal(yamlBuf.Bytes(), &fromYAML))
+	yamlAsJSON, err := json.Marshal(fromYAML)
+	require.NoError(t, err)
+	assert.JSONEq(t, jsonBuf.String(), string(yamlAsJSON))
+
+	out := yamlBuf.String()
+	assert.Contains(t, out, "script: |\n")
+	assert.Contains(t, out, "            /opt/CrowdStrike/falconctl -sf --cid=cid --tags=\"a b\"\n")
+	assert.Contains(t, out, `generation: "1"`)
+	assert.NotContains(t, out, `script: "`)
+}
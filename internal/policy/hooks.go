@@ -0,0 +1,101 @@
+package policy
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// Hooks are site specific steps added to the resource group of an os.
+type Hooks struct {
+	// PreInstall runs before the sensor is staged or installed, e.g. to trust a proxy CA bundle.
+	PreInstall *Hook `yaml:"preInstall" json:"preInstall,omitempty"`
+	// PostConfigure runs after the sensor is configured, e.g. to run a health check.
+	PostConfigure *Hook `yaml:"postConfigure" json:"postConfigure,omitempty"`
+}
+
+// Hook is an exec resource. Scripts are text/templates rendered with the Policy and must
+// follow the same exit code conventions as the built-in resources: validate exits 100 when
+// the hook has already been applied and 101 otherwise, enforce exits 100 on success.
+type Hook struct {
+	Validate string `yaml:"validate" json:"validate"`
+	Enforce  string `yaml:"enforce" json:"enforce"`
+	// Interpreter defaults to SHELL on linux and POWERSHELL on windows.
+	Interpreter string `yaml:"interpreter" json:"interpreter,omitempty"`
+}
+
+// hooksFor returns the most specific hooks of the target, see osTarget.keys.
+func (p Policy) hooksFor(t osTarget) Hooks {
+	for _, key := range t.keys() {
+		if h, ok := p.Hooks[key]; ok {
+			return h
+		}
+	}
+	return Hooks{}
+}
+
+// withHooks adds the pre-install and post-configure hooks of the target to the resource group.
+func (p Policy) withHooks(t osTarget, group ResourceGroup) (ResourceGroup, error) {
+	hooks := p.hooksFor(t)
+
+	if hooks.PreInstall != nil {
+		r, err := p.hookResource(t, t.ID+"-pre-install-hook", *hooks.PreInstall)
+		if err != nil {
+			return group, err
+		}
+		group.Resources = append([]Resource{r}, group.Resources...)
+	}
+
+	if hooks.PostConfigure != nil {
+		r, err := p.hookResource(t, t.ID+"-post-configure-hook", *hooks.PostConfigure)
+		if err != nil {
+			return group, err
+		}
+		group.Resources = append(group.Resources, r)
+	}
+
+	return group, nil
+}
+
+func (p Policy) hookResource(t osTarget, id string, h Hook) (Resource, error) {
+	interpreter := h.Interpreter
+	if interpreter == "" {
+		interpreter = InterpreterShell
+		if t.Platform == PlatformWindows {
+			interpreter = InterpreterPowerShell
+		}
+	}
+
+	validate, err := p.render(id+" validate", h.Validate)
+	if err != nil {
+		return Resource{}, err
+	}
+
+	enforce, err := p.render(id+" enforce", h.Enforce)
+	if err != nil {
+		return Resource{}, err
+	}
+
+	return Resource{
+		ID: id,
+		Exec: &ExecResource{
+			Validate: Exec{Script: validate, Interpreter: strings.ToUpper(interpreter)},
+			Enforce:  &Exec{Script: enforce, Interpreter: strings.ToUpper(interpreter)},
+		},
+	}, nil
+}
+
+// render executes text as a template with the Policy as data.
+func (p Policy) render(name string, text string) (string, error) {
+	t, err := template.New(name).Funcs(templateFuncs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse %s: %w", name, err)
+	}
+
+	var b strings.Builder
+	if err := t.Execute(&b, p); err != nil {
+		return "", fmt.Errorf("failed to render %s: %w", name, err)
+	}
+
+	return b.String(), nil
+}
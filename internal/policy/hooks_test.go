@@ -0,0 +1,75 @@
+package policy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func resourceIDs(a OSPolicyAssignment, family string, version string) []string {
+	var ids []string
+	for _, g := range a.OSPolicies[0].ResourceGroups {
+		if g.InventoryFilters[0].OSShortName == family && g.InventoryFilters[0].OSVersion == version {
+			for _, r := range g.Resources {
+				ids = append(ids, r.ID)
+			}
+		}
+	}
+	return ids
+}
+
+func TestWithHooks(t *testing.T) {
+	p := NewPolicy("cid", "", "", SensorOptions{}, stagedSensors(), nil, nil)
+	p.Hooks = map[string]Hooks{
+		"linux": {
+			PostConfigure: &Hook{
+				Validate: "test -f /tmp/checked && exit 100 || exit 101",
+				Enforce:  "/opt/CrowdStrike/falconctl -g --cid | grep -qi {{ .Cid }} && exit 100",
+			},
+		},
+		"rhel": {
+			PreInstall: &Hook{
+				Validate: "test -f /etc/pki/proxy.pem && exit 100 || exit 101",
+				Enforce:  "cp /tmp/proxy.pem /etc/pki/proxy.pem && exit 100",
+			},
+		},
+		"windows": {
+			PreInstall: &Hook{Validate: "Exit 100\nExit 101", Enforce: "Exit 100"},
+		},
+	}
+
+	a, err := p.OSPolicyAssignment()
+	require.NoError(t, err)
+	require.NoError(t, a.Validate())
+
+	// the family hooks replace the platform hooks.
+	assert.Equal(t, []string{"rhel9-pre-install-hook", "rhel9-install", "rhel9-configure", "rhel9-health"}, resourceIDs(a, "rhel", "9*"))
+	assert.Equal(
+		t,
+		[]string{"ubuntu-install", "ubuntu-configure", "ubuntu-health", "ubuntu-post-configure-hook"},
+		resourceIDs(a, "ubuntu", ""),
+	)
+	assert.Equal(
+		t,
+		[]string{"windows-pre-install-hook", "windows-stage-installer", "windows-install", "windows-health"},
+		resourceIDs(a, "windows", ""),
+	)
+
+	hook := findResource(t, a, "ubuntu-post-configure-hook")
+	assert.Equal(t, "/opt/CrowdStrike/falconctl -g --cid | grep -qi cid && exit 100", hook.Exec.Enforce.Script)
+	assert.Equal(t, InterpreterShell, hook.Exec.Enforce.Interpreter)
+	assert.Equal(t, InterpreterPowerShell, findResource(t, a, "windows-pre-install-hook").Exec.Validate.Interpreter)
+}
+
+func TestWithHooks_Invalid(t *testing.T) {
+	p := NewPolicy("cid", "", "", SensorOptions{}, stagedSensors(), nil, nil)
+
+	p.Hooks = map[string]Hooks{"ubuntu": {PreInstall: &Hook{Validate: "{{ .Missing }}", Enforce: "exit 100"}}}
+	_, err := p.OSPolicyAssignment()
+	assert.ErrorContains(t, err, "ubuntu-pre-install-hook validate")
+
+	p.Hooks = map[string]Hooks{"ubuntu": {PreInstall: &Hook{Validate: "true", Enforce: "exit 100"}}}
+	err = p.Validate()
+	assert.ErrorContains(t, err, "resource ubuntu-pre-install-hook: exec.validate.script")
+}
@@ -0,0 +1,110 @@
+package policy
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// hasMaintenanceToken returns true if the scripts need a maintenance token.
+func (p Policy) hasMaintenanceToken() bool {
+	return p.MaintenanceTokenSecret != "" || p.HasPlaintextMaintenanceTokens()
+}
+
+// HasPlaintextMaintenanceTokens returns true if maintenance tokens are written into the policy
+// instead of being read from Secret Manager. Anyone who can view the assignment can read them.
+func (p Policy) HasPlaintextMaintenanceTokens() bool {
+	return p.MaintenanceToken != "" || len(p.MaintenanceTokens) > 0
+}
+
+// validateMaintenanceToken checks the maintenance token secret.
+func (p Policy) validateMaintenanceToken() error {
+	if p.MaintenanceTokenSecret == "" {
+		return nil
+	}
+
+	if !secretPattern.MatchString(p.MaintenanceTokenSecret) {
+		return fmt.Errorf(
+			"invalid maintenance token secret %q: must be projects/<project>/secrets/<secret>[/versions/<version>]",
+			p.MaintenanceTokenSecret,
+		)
+	}
+
+	if p.MaintenanceToken != "" {
+		return errors.New("maintenance token can not be combined with a maintenance token secret")
+	}
+
+	return nil
+}
+
+// linuxMaintenanceTokenScript sets the maintenance token with falconctl so a protected sensor can
+// be removed or upgraded. The host's own token is used when it is in MaintenanceTokens, otherwise
+// the bulk MaintenanceToken.
+func (p Policy) linuxMaintenanceTokenScript() string {
+	if !p.hasMaintenanceToken() {
+		return ""
+	}
+
+	var b strings.Builder
+	if p.MaintenanceTokenSecret != "" {
+		b.WriteString(linuxMetadataTokenScript)
+		b.WriteString(linuxReadSecretScript(secretVersion(p.MaintenanceTokenSecret), "maintenance_token"))
+		b.WriteString("unset metadata_token\n")
+	} else {
+		b.WriteString("maintenance_token=" + shellQuote(p.MaintenanceToken) + "\n")
+	}
+
+	if len(p.MaintenanceTokens) > 0 {
+		b.WriteString(`aid=$(/opt/CrowdStrike/falconctl -g --aid 2>/dev/null | sed -nE 's/.*aid="?([0-9a-fA-F]+)"?.*/\1/p')
+case "${aid}" in
+`)
+		for _, aid := range slices.Sorted(maps.Keys(p.MaintenanceTokens)) {
+			b.WriteString("  " + shellQuote(strings.ToLower(aid)) + ") maintenance_token=" + shellQuote(p.MaintenanceTokens[aid]) + " ;;\n")
+		}
+		b.WriteString("esac\n")
+	}
+
+	b.WriteString(`if [ -n "${maintenance_token}" ] && [ -x /opt/CrowdStrike/falconctl ]; then
+  /opt/CrowdStrike/falconctl -s -f --maintenance-token="${maintenance_token}" >/dev/null
+fi
+unset maintenance_token
+`)
+	return b.String()
+}
+
+// windowsMaintenanceTokenScript appends the MAINTENANCE_TOKEN to the installer arguments in
+// variable, see linuxMaintenanceTokenScript.
+func (p Policy) windowsMaintenanceTokenScript(variable string) string {
+	if !p.hasMaintenanceToken() {
+		return ""
+	}
+
+	var b strings.Builder
+	if p.MaintenanceTokenSecret != "" {
+		b.WriteString(windowsMetadataTokenScript)
+		b.WriteString(windowsReadSecretScript(secretVersion(p.MaintenanceTokenSecret), "maintenanceToken"))
+		b.WriteString("Remove-Variable metadataToken\n")
+	} else {
+		b.WriteString("$maintenanceToken = " + powershellQuote(p.MaintenanceToken) + "\n")
+	}
+
+	if len(p.MaintenanceTokens) > 0 {
+		b.WriteString(windowsAidScript + `if ($agentId) {
+    $aid = ([System.BitConverter]::ToString($agentId) -replace '-', '').ToLower()
+    switch ($aid) {
+`)
+		for _, aid := range slices.Sorted(maps.Keys(p.MaintenanceTokens)) {
+			b.WriteString("        " + powershellQuote(strings.ToLower(aid)) + " { $maintenanceToken = " + powershellQuote(p.MaintenanceTokens[aid]) + " }\n")
+		}
+		b.WriteString("    }\n}\n")
+	}
+
+	b.WriteString(`if ($maintenanceToken) {
+    $` + variable + ` += "MAINTENANCE_TOKEN=$maintenanceToken"
+}
+Remove-Variable maintenanceToken
+`)
+	return b.String()
+}
@@ -0,0 +1,106 @@
+package policy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMaintenanceToken(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  func(p *Policy)
+		linux   []string
+		windows []string
+	}{
+		{
+			name: "bulk token",
+			policy: func(p *Policy) {
+				p.MaintenanceToken = "it's-a-token"
+			},
+			linux:   []string{`maintenance_token='it'\''s-a-token'`, `--maintenance-token="${maintenance_token}"`},
+			windows: []string{`$maintenanceToken = 'it''s-a-token'`, `$uninstallArguments += "MAINTENANCE_TOKEN=$maintenanceToken"`},
+		},
+		{
+			name: "host tokens",
+			policy: func(p *Policy) {
+				p.MaintenanceTokens = map[string]string{"B2": "token-b", "a1": "token-a"}
+			},
+			linux:   []string{"maintenance_token=''", "  b2) maintenance_token=token-b ;;", "  a1) maintenance_token=token-a ;;"},
+			windows: []string{"$maintenanceToken = ''", "'b2' { $maintenanceToken = 'token-b' }", "'a1' { $maintenanceToken = 'token-a' }"},
+		},
+		{
+			name: "bulk token secret",
+			policy: func(p *Policy) {
+				p.MaintenanceTokenSecret = "projects/p/secrets/maintenance-token"
+			},
+			linux: []string{
+				`maintenance_token=$(curl -sSf -H "Authorization: Bearer ${metadata_token}" "https://secretmanager.googleapis.com/v1/projects/p/secrets/maintenance-token/versions/latest:access"`,
+				`--maintenance-token="${maintenance_token}"`,
+			},
+			windows: []string{
+				"-Uri 'https://secretmanager.googleapis.com/v1/projects/p/secrets/maintenance-token/versions/latest:access'",
+				"$maintenanceToken = [System.Text.Encoding]::UTF8.GetString(",
+				`$uninstallArguments += "MAINTENANCE_TOKEN=$maintenanceToken"`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewPolicy("cid", "", "", SensorOptions{}, nil, nil, nil)
+			p.Uninstall = true
+			tt.policy(&p)
+
+			assignment, err := p.OSPolicyAssignment()
+			require.NoError(t, err)
+
+			linux := findResource(t, assignment, "rhel9-uninstall").Exec.Enforce.Script
+			for _, s := range tt.linux {
+				assert.Contains(t, linux, s)
+			}
+
+			windows := findResource(t, assignment, "windows-uninstall").Exec.Enforce.Script
+			for _, s := range tt.windows {
+				assert.Contains(t, windows, s)
+			}
+		})
+	}
+}
+
+func TestMaintenanceTokenSecretValidation(t *testing.T) {
+	p := NewPolicy("cid", "", "", SensorOptions{}, nil, nil, nil)
+	p.Uninstall = true
+	p.MaintenanceTokenSecret = "maintenance-token"
+
+	_, err := p.OSPolicyAssignment()
+	assert.ErrorContains(t, err, `invalid maintenance token secret "maintenance-token"`)
+
+	p.MaintenanceTokenSecret = "projects/p/secrets/maintenance-token"
+	p.MaintenanceToken = "token"
+	_, err = p.OSPolicyAssignment()
+	assert.EqualError(t, err, "maintenance token can not be combined with a maintenance token secret")
+
+	p.MaintenanceToken = ""
+	assignment, err := p.OSPolicyAssignment()
+	require.NoError(t, err)
+	assert.False(t, p.HasPlaintextMaintenanceTokens())
+	assert.NotContains(t, findResource(t, assignment, "rhel9-uninstall").Exec.Enforce.Script, "maintenance_token=''")
+}
+
+func TestMaintenanceTokenUpgrade(t *testing.T) {
+	p := NewPolicy("cid", "", "", SensorOptions{}, stagedSensors(), nil, nil)
+
+	assignment, err := p.OSPolicyAssignment()
+	require.NoError(t, err)
+	assert.NotContains(t, findResource(t, assignment, "windows-install").Exec.Enforce.Script, "MAINTENANCE_TOKEN")
+
+	p.EnforceVersion = true
+	p.MaintenanceToken = "token"
+
+	assignment, err = p.OSPolicyAssignment()
+	require.NoError(t, err)
+	assert.Contains(t, findResource(t, assignment, "ubuntu-install").Exec.Enforce.Script, "--maintenance-token=")
+	assert.Contains(t, findResource(t, assignment, "windows-install").Exec.Enforce.Script, `$installArguments += "MAINTENANCE_TOKEN=$maintenanceToken"`)
+}
@@ -0,0 +1,54 @@
+package policy
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// ModeEnforcement installs and configures the sensor.
+	ModeEnforcement = "ENFORCEMENT"
+	// ModeValidation only reports whether VMs are compliant without changing them.
+	ModeValidation = "VALIDATION"
+)
+
+const (
+	DeployAssignmentPrefix = "crowdstrike-sensor-deploy"
+	AuditAssignmentPrefix  = "crowdstrike-sensor-audit"
+	// DecommissionAssignmentPrefix names the assignments that remove the sensor.
+	DecommissionAssignmentPrefix = "crowdstrike-sensor-decommission"
+)
+
+// ParseMode parses validation or enforcement into the os policy mode.
+func ParseMode(s string) (string, error) {
+	switch strings.ToUpper(strings.TrimSpace(s)) {
+	case "", ModeEnforcement:
+		return ModeEnforcement, nil
+	case ModeValidation:
+		return ModeValidation, nil
+	}
+
+	return "", fmt.Errorf("invalid mode %q: must be one of validation, enforcement", s)
+}
+
+// AssignmentPrefix returns the assignment name prefix for the mode so audit and enforcement
+// assignments can exist side by side.
+func AssignmentPrefix(mode string) string {
+	if mode == ModeValidation {
+		return AuditAssignmentPrefix
+	}
+	return DeployAssignmentPrefix
+}
+
+// SensorMissing returns true if any of the non-compliant resources of a VM install or configure the sensor.
+//
+// Resources that only stage the installer are ignored so a VM with a sensor installed by other
+// means is not reported as missing it.
+func SensorMissing(nonCompliantResources []string) bool {
+	for _, id := range nonCompliantResources {
+		if strings.HasSuffix(id, "-install") || strings.HasSuffix(id, "-configure") {
+			return true
+		}
+	}
+	return false
+}
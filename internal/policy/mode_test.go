@@ -0,0 +1,52 @@
+package policy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseMode(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{input: "", want: ModeEnforcement},
+		{input: "enforcement", want: ModeEnforcement},
+		{input: "VALIDATION", want: ModeValidation},
+		{input: " validation ", want: ModeValidation},
+		{input: "audit", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseMode(tt.input)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestSensorMissing(t *testing.T) {
+	tests := []struct {
+		name      string
+		resources []string
+		want      bool
+	}{
+		{name: "compliant", resources: nil, want: false},
+		{name: "stage only", resources: []string{"rhel9-stage-installer"}, want: false},
+		{name: "install", resources: []string{"rhel9-stage-installer", "rhel9-install"}, want: true},
+		{name: "configure", resources: []string{"ubuntu-configure"}, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, SensorMissing(tt.resources))
+		})
+	}
+}
@@ -0,0 +1,255 @@
+package policy
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+var (
+	tagPattern   = regexp.MustCompile(`^[A-Za-z0-9/_-]+$`)
+	hostPattern  = regexp.MustCompile(`^[A-Za-z0-9.:_\[\]-]+$`)
+	tokenPattern = regexp.MustCompile(`^[A-Za-z0-9]+$`)
+	// secretPattern matches Secret Manager secret and secret version resource names.
+	secretPattern = regexp.MustCompile(`^projects/[A-Za-z0-9_-]+/secrets/[A-Za-z0-9_-]+(/versions/[A-Za-z0-9_-]+)?$`)
+)
+
+// SensorBackends are the Linux sensor backends accepted by --sensor-backend.
+var SensorBackends = []string{"auto", "bpf", "kernel"}
+
+// BillingTypes are the billing types accepted by --billing.
+var BillingTypes = []string{"default", "metered"}
+
+// SensorOptions are sensor settings applied at install time. They are translated into falconctl
+// options on Linux and installer arguments on Windows.
+type SensorOptions struct {
+	// Tags are the sensor grouping tags.
+	Tags              []string
+	ProxyHost         string
+	ProxyPort         int
+	DisableProxy      bool
+	ProvisioningToken string
+	// ProvisioningTokenSecret is a Secret Manager secret the provisioning token is read from on the
+	// VM at enforce time, so the token is not stored in the policy.
+	ProvisioningTokenSecret string
+	// Backend is the Linux sensor backend one of SensorBackends. Ignored on Windows.
+	Backend string
+	// Billing is the billing type one of BillingTypes.
+	Billing string
+}
+
+// Validate checks the options contain values the sensor accepts.
+func (o SensorOptions) Validate() error {
+	var errs []error
+
+	for _, tag := range o.Tags {
+		if !tagPattern.MatchString(tag) {
+			errs = append(errs, fmt.Errorf("invalid tag %q: tags may only contain letters, numbers, /, _ and -", tag))
+		}
+	}
+
+	if len(strings.Join(o.Tags, ",")) > 256 {
+		errs = append(errs, errors.New("invalid tags: must be at most 256 characters combined"))
+	}
+
+	if o.ProxyHost != "" && !hostPattern.MatchString(o.ProxyHost) {
+		errs = append(errs, fmt.Errorf("invalid proxy host %q", o.ProxyHost))
+	}
+
+	if o.ProxyPort < 0 || o.ProxyPort > 65535 {
+		errs = append(errs, fmt.Errorf("invalid proxy port %d: must be between 1 and 65535", o.ProxyPort))
+	}
+
+	if o.ProxyPort > 0 && o.ProxyHost == "" {
+		errs = append(errs, errors.New("proxy port requires a proxy host"))
+	}
+
+	if o.DisableProxy && (o.ProxyHost != "" || o.ProxyPort > 0) {
+		errs = append(errs, errors.New("disable proxy can not be combined with a proxy host or port"))
+	}
+
+	if o.ProvisioningToken != "" && !tokenPattern.MatchString(o.ProvisioningToken) {
+		errs = append(errs, errors.New("invalid provisioning token: must only contain letters and numbers"))
+	}
+
+	if o.ProvisioningTokenSecret != "" && !secretPattern.MatchString(o.ProvisioningTokenSecret) {
+		errs = append(
+			errs,
+			fmt.Errorf(
+				"invalid provisioning token secret %q: must be projects/<project>/secrets/<secret>[/versions/<version>]",
+				o.ProvisioningTokenSecret,
+			),
+		)
+	}
+
+	if o.ProvisioningToken != "" && o.ProvisioningTokenSecret != "" {
+		errs = append(errs, errors.New("provisioning token can not be combined with a provisioning token secret"))
+	}
+
+	if o.Backend != "" && !slices.Contains(SensorBackends, o.Backend) {
+		errs = append(
+			errs,
+			fmt.Errorf("invalid sensor backend %q: must be one of %s", o.Backend, strings.Join(SensorBackends, ", ")),
+		)
+	}
+
+	if o.Billing != "" && !slices.Contains(BillingTypes, o.Billing) {
+		errs = append(
+			errs,
+			fmt.Errorf("invalid billing type %q: must be one of %s", o.Billing, strings.Join(BillingTypes, ", ")),
+		)
+	}
+
+	return errors.Join(errs...)
+}
+
+// LinuxArgs returns the options as falconctl arguments, quoted for a shell script.
+func (o SensorOptions) LinuxArgs() []string {
+	var args []string
+
+	if len(o.Tags) > 0 {
+		args = append(args, "--tags="+shellQuote(strings.Join(o.Tags, ",")))
+	}
+
+	if o.ProxyHost != "" {
+		args = append(args, "--apd=false", "--aph="+shellQuote(o.ProxyHost))
+	}
+
+	if o.ProxyPort > 0 {
+		args = append(args, "--app="+strconv.Itoa(o.ProxyPort))
+	}
+
+	if o.DisableProxy {
+		args = append(args, "--apd=true")
+	}
+
+	if o.ProvisioningToken != "" {
+		args = append(args, "--provisioning-token="+shellQuote(o.ProvisioningToken))
+	}
+
+	if o.Backend != "" {
+		args = append(args, "--backend="+o.Backend)
+	}
+
+	if o.Billing != "" {
+		args = append(args, "--billing="+o.Billing)
+	}
+
+	return args
+}
+
+// WindowsArgs returns the options as installer arguments. The arguments are not quoted,
+// see formatWinArgs.
+func (o SensorOptions) WindowsArgs() []string {
+	var args []string
+
+	if len(o.Tags) > 0 {
+		args = append(args, "GROUPING_TAGS="+strings.Join(o.Tags, ","))
+	}
+
+	if o.ProxyHost != "" {
+		args = append(args, "APP_PROXYNAME="+o.ProxyHost)
+	}
+
+	if o.ProxyPort > 0 {
+		args = append(args, "APP_PROXYPORT="+strconv.Itoa(o.ProxyPort))
+	}
+
+	if o.DisableProxy {
+		args = append(args, "PROXYDISABLE=1")
+	}
+
+	if o.ProvisioningToken != "" {
+		args = append(args, "ProvToken="+o.ProvisioningToken)
+	}
+
+	if o.Billing != "" {
+		// the windows installer expects Default or Metered.
+		args = append(args, "BILLINGTYPE="+strings.ToUpper(o.Billing[:1])+o.Billing[1:])
+	}
+
+	return args
+}
+
+// secretArg is an install argument whose value is read from Secret Manager on the VM.
+type secretArg struct {
+	// Secret is the secret version resource name.
+	Secret string
+	// Variable is the script variable the value is read into.
+	Variable   string
+	LinuxFlag  string
+	WindowsArg string
+}
+
+// secretArgs returns the install arguments that are read from Secret Manager.
+func (o SensorOptions) secretArgs() []secretArg {
+	var args []secretArg
+
+	if o.ProvisioningTokenSecret != "" {
+		args = append(args, secretArg{
+			Secret:     secretVersion(o.ProvisioningTokenSecret),
+			Variable:   "FALCON_PROVISIONING_TOKEN",
+			LinuxFlag:  "--provisioning-token",
+			WindowsArg: "ProvToken",
+		})
+	}
+
+	return args
+}
+
+// secretVersion returns the secret version resource name, defaulting to the latest version.
+func secretVersion(name string) string {
+	if strings.Contains(name, "/versions/") {
+		return name
+	}
+	return name + "/versions/latest"
+}
+
+// shellQuote single quotes s for a POSIX shell unless it only contains safe characters.
+func shellQuote(s string) string {
+	if s != "" && strings.Trim(s, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789,./:=_-") == "" {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// powershellQuote single quotes s for PowerShell.
+func powershellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// splitArgs splits user supplied install params on whitespace, keeping double quoted values
+// such as GROUPING_TAGS="a,b c" together.
+func splitArgs(s string) []string {
+	var args []string
+	var current strings.Builder
+	inQuotes := false
+	started := false
+
+	for _, r := range s {
+		switch {
+		case r == '"':
+			inQuotes = !inQuotes
+			started = true
+			current.WriteRune(r)
+		case !inQuotes && (r == ' ' || r == '\t' || r == '\n'):
+			if started {
+				args = append(args, current.String())
+				current.Reset()
+				started = false
+			}
+		default:
+			started = true
+			current.WriteRune(r)
+		}
+	}
+
+	if started {
+		args = append(args, current.String())
+	}
+
+	return args
+}
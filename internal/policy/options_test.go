@@ -0,0 +1,136 @@
+package policy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSensorOptions_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		options SensorOptions
+		wantErr bool
+	}{
+		{name: "empty", options: SensorOptions{}},
+		{
+			name: "all",
+			options: SensorOptions{
+				Tags:              []string{"Washington/DC_USA", "Production"},
+				ProxyHost:         "proxy.example.com",
+				ProxyPort:         8080,
+				ProvisioningToken: "abcd1234",
+				Backend:           "bpf",
+				Billing:           "metered",
+			},
+		},
+		{name: "tag with space", options: SensorOptions{Tags: []string{"Data Center"}}, wantErr: true},
+		{name: "tag with quote", options: SensorOptions{Tags: []string{`prod"`}}, wantErr: true},
+		{name: "port without host", options: SensorOptions{ProxyPort: 8080}, wantErr: true},
+		{name: "port out of range", options: SensorOptions{ProxyHost: "proxy", ProxyPort: 70000}, wantErr: true},
+		{name: "disable with host", options: SensorOptions{ProxyHost: "proxy", DisableProxy: true}, wantErr: true},
+		{name: "token with symbols", options: SensorOptions{ProvisioningToken: "abc; rm -rf /"}, wantErr: true},
+		{name: "token secret", options: SensorOptions{ProvisioningTokenSecret: "projects/p/secrets/token/versions/3"}},
+		{name: "invalid token secret", options: SensorOptions{ProvisioningTokenSecret: "falcon-token"}, wantErr: true},
+		{
+			name:    "token and token secret",
+			options: SensorOptions{ProvisioningToken: "abcd1234", ProvisioningTokenSecret: "projects/p/secrets/token"},
+			wantErr: true,
+		},
+		{name: "unknown backend", options: SensorOptions{Backend: "ebpf"}, wantErr: true},
+		{name: "unknown billing", options: SensorOptions{Billing: "free"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.options.Validate()
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestSensorOptions_Args(t *testing.T) {
+	o := SensorOptions{
+		Tags:              []string{"Washington/DC_USA", "Production"},
+		ProxyHost:         "proxy.example.com",
+		ProxyPort:         8080,
+		ProvisioningToken: "abcd1234",
+		Backend:           "bpf",
+		Billing:           "metered",
+	}
+
+	assert.Equal(t, []string{
+		"--tags=Washington/DC_USA,Production",
+		"--apd=false",
+		"--aph=proxy.example.com",
+		"--app=8080",
+		"--provisioning-token=abcd1234",
+		"--backend=bpf",
+		"--billing=metered",
+	}, o.LinuxArgs())
+
+	assert.Equal(t, []string{
+		"GROUPING_TAGS=Washington/DC_USA,Production",
+		"APP_PROXYNAME=proxy.example.com",
+		"APP_PROXYPORT=8080",
+		"ProvToken=abcd1234",
+		"BILLINGTYPE=Metered",
+	}, o.WindowsArgs())
+
+	assert.Equal(t, []string{"--apd=true"}, SensorOptions{DisableProxy: true}.LinuxArgs())
+	assert.Equal(t, []string{"PROXYDISABLE=1"}, SensorOptions{DisableProxy: true}.WindowsArgs())
+}
+
+func TestFormatWinArgs(t *testing.T) {
+	tests := []struct {
+		name    string
+		options SensorOptions
+		args    string
+		want    string
+	}{
+		{
+			name: "no args",
+			want: "'/install', '/quiet', '/norestart', 'CID=cid'",
+		},
+		{
+			name: "quoted tags with spaces",
+			args: `GROUPING_TAGS="Data Center,Production"  APP_PROXYNAME=proxy`,
+			want: `'/install', '/quiet', '/norestart', 'CID=cid', 'GROUPING_TAGS="Data Center,Production"', 'APP_PROXYNAME=proxy'`,
+		},
+		{
+			name: "already quoted",
+			args: `'NO_START=1'`,
+			want: "'/install', '/quiet', '/norestart', 'CID=cid', 'NO_START=1'",
+		},
+		{
+			name: "single quote",
+			args: `GROUPING_TAGS=o'brien`,
+			want: "'/install', '/quiet', '/norestart', 'CID=cid', 'GROUPING_TAGS=o''brien'",
+		},
+		{
+			name:    "options before args",
+			options: SensorOptions{Tags: []string{"prod"}},
+			args:    "NO_START=1",
+			want:    "'/install', '/quiet', '/norestart', 'CID=cid', 'GROUPING_TAGS=prod', 'NO_START=1'",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, formatWinArgs("cid", tt.options, tt.args))
+		})
+	}
+}
+
+func TestFormatLinuxArgs(t *testing.T) {
+	assert.Equal(t, "--cid=cid", formatLinuxArgs("cid", SensorOptions{}, " "))
+	assert.Equal(
+		t,
+		"--cid=cid --tags=prod --aph=proxy",
+		formatLinuxArgs("cid", SensorOptions{Tags: []string{"prod"}}, "--aph=proxy"),
+	)
+}
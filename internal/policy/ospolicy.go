@@ -0,0 +1,178 @@
+package policy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// The types below model the subset of the GCP OS policy assignment resource used to deploy the
+// Falcon sensor. Field names follow the json accepted by
+// `gcloud compute os-config os-policy-assignments create --file`.
+//
+// See https://cloud.google.com/compute/docs/os-configuration-management/working-with-os-policies
+
+const (
+	InterpreterShell      = "SHELL"
+	InterpreterPowerShell = "POWERSHELL"
+
+	PackageInstalled = "INSTALLED"
+
+	FileContentsMatch = "CONTENTS_MATCH"
+)
+
+// OSPolicyAssignment is the file passed to gcloud to create an os policy assignment.
+type OSPolicyAssignment struct {
+	OSPolicies     []OSPolicy     `json:"osPolicies"`
+	InstanceFilter InstanceFilter `json:"instanceFilter"`
+	Rollout        Rollout        `json:"rollout"`
+}
+
+type OSPolicy struct {
+	ID             string          `json:"id"`
+	Mode           string          `json:"mode"`
+	ResourceGroups []ResourceGroup `json:"resourceGroups"`
+}
+
+// ResourceGroup is a set of resources applied to VMs matching any of the inventory filters.
+type ResourceGroup struct {
+	InventoryFilters []InventoryFilter `json:"inventoryFilters"`
+	Resources        []Resource        `json:"resources"`
+}
+
+type InventoryFilter struct {
+	OSShortName string `json:"osShortName"`
+	OSVersion   string `json:"osVersion,omitempty"`
+}
+
+// Resource is an os policy resource. Only one of Pkg, File or Exec is set.
+type Resource struct {
+	ID   string        `json:"id"`
+	Pkg  *PkgResource  `json:"pkg,omitempty"`
+	File *FileResource `json:"file,omitempty"`
+	Exec *ExecResource `json:"exec,omitempty"`
+}
+
+// PkgResource installs a package. Only one of Rpm or Deb is set.
+type PkgResource struct {
+	DesiredState string         `json:"desiredState"`
+	Rpm          *PackageSource `json:"rpm,omitempty"`
+	Deb          *PackageSource `json:"deb,omitempty"`
+}
+
+type PackageSource struct {
+	Source   File `json:"source"`
+	PullDeps bool `json:"pullDeps,omitempty"`
+}
+
+// FileResource stages a file on the VM.
+type FileResource struct {
+	File        File   `json:"file"`
+	Path        string `json:"path"`
+	State       string `json:"state"`
+	Permissions string `json:"permissions,omitempty"`
+}
+
+type File struct {
+	Gcs *GcsObject `json:"gcs,omitempty"`
+}
+
+type GcsObject struct {
+	Bucket     string `json:"bucket"`
+	Object     string `json:"object"`
+	Generation int64  `json:"generation,string"`
+}
+
+// UnmarshalJSON accepts the generation as a string or a number since gcloud accepts both.
+func (o *GcsObject) UnmarshalJSON(b []byte) error {
+	var raw struct {
+		Bucket     string          `json:"bucket"`
+		Object     string          `json:"object"`
+		Generation json.RawMessage `json:"generation"`
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&raw); err != nil {
+		return err
+	}
+
+	o.Bucket = raw.Bucket
+	o.Object = raw.Object
+	o.Generation = 0
+
+	if len(raw.Generation) == 0 {
+		return nil
+	}
+
+	var generation string
+	if err := json.Unmarshal(raw.Generation, &generation); err != nil {
+		generation = string(raw.Generation)
+	}
+
+	if generation == "" || generation == "null" {
+		return nil
+	}
+
+	g, err := strconv.ParseInt(generation, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid gcs generation %s: %w", raw.Generation, err)
+	}
+	o.Generation = g
+
+	return nil
+}
+
+// ExecResource runs the enforce script when the validate script exits with 101.
+type ExecResource struct {
+	Validate Exec  `json:"validate"`
+	Enforce  *Exec `json:"enforce,omitempty"`
+}
+
+type Exec struct {
+	Script      string `json:"script"`
+	Interpreter string `json:"interpreter"`
+}
+
+// InstanceFilter selects the VMs in the zone the assignment applies to.
+type InstanceFilter struct {
+	All             bool       `json:"all,omitempty"`
+	InclusionLabels []LabelSet `json:"inclusionLabels,omitempty"`
+	ExclusionLabels []LabelSet `json:"exclusionLabels,omitempty"`
+}
+
+// LabelSet matches VMs that have all of the labels.
+type LabelSet struct {
+	Labels map[string]string `json:"labels"`
+}
+
+// Matches returns true if labels contain every label of the set.
+func (s LabelSet) Matches(labels map[string]string) bool {
+	for name, value := range s.Labels {
+		if v, ok := labels[name]; !ok || v != value {
+			return false
+		}
+	}
+	return true
+}
+
+// MatchesLabels returns true if a VM with the labels is selected by the filter: it matches any
+// of the inclusion label sets, or there are none, and none of the exclusion label sets.
+func (f InstanceFilter) MatchesLabels(labels map[string]string) bool {
+	for _, s := range f.ExclusionLabels {
+		if s.Matches(labels) {
+			return false
+		}
+	}
+
+	if len(f.InclusionLabels) == 0 {
+		return true
+	}
+	for _, s := range f.InclusionLabels {
+		if s.Matches(labels) {
+			return true
+		}
+	}
+	return false
+}
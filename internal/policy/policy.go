@@ -3,61 +3,92 @@ package policy
 import (
 	"bytes"
 	"context"
-	_ "embed"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"os/exec"
-	"runtime"
+	"path"
+	"slices"
 	"strings"
 	"sync"
-	"text/template"
 
+	"github.com/crowdstrike/gcp-os-policy/internal/logging"
 	"github.com/crowdstrike/gcp-os-policy/internal/sensor"
 )
 
-//go:embed template.json
-var policyTemplate string
+// PolicyID is the id of the os policy within the assignment.
+const PolicyID = "crowdstrike-falcon-sensor-deploy"
 
 type osResource struct {
 	Bucket     string
 	Object     string
 	Generation int64
-}
-
-type LabelSet struct {
-	Label string
-	Value string
+	// Version is the version of the staged sensor, e.g. 7.10.17706.
+	Version string
 }
 
 type Policy struct {
 	Cid                  string
+	Mode                 string
 	LinuxInstallParams   string
 	WindowsInstallParams string
-	Sles12               osResource
-	Sles15               osResource
-	Rhel7                osResource
-	Rhel8                osResource
-	Rhel9                osResource
-	Rhel10               osResource
-	Oracle7              osResource
-	Oracle8              osResource
-	Oracle9              osResource
-	Oracle10             osResource
-	Debian               osResource
-	Ubuntu               osResource
-	Centos8              osResource
-	Centos9              osResource
-	Centos10             osResource
-	Windows              osResource
-	ExclusionLabelSets   []LabelSet
-	InclusionLabelSets   []LabelSet
+	// EnforceVersion upgrades sensors older than the staged sensor in place instead of leaving
+	// upgrades to Sensor Update Policies.
+	EnforceVersion bool
+	// Uninstall removes the sensor instead of installing it.
+	Uninstall bool
+	// SigningKey is the staged CrowdStrike signing key. When set the Linux installers are only
+	// installed once their signature has been verified.
+	SigningKey osResource
+	// MaintenanceToken is the bulk maintenance token that allows protected sensors to be removed
+	// or upgraded.
+	MaintenanceToken string
+	// MaintenanceTokens are the maintenance tokens of individual hosts keyed by AID. Hosts that
+	// are not listed use MaintenanceToken.
+	MaintenanceTokens map[string]string
+	// MaintenanceTokenSecret is a Secret Manager secret the bulk maintenance token is read from on
+	// the VM, so it is not written into the policy. Replaces MaintenanceToken.
+	MaintenanceTokenSecret string
+	// Options are the typed sensor options included in the install params of every os.
+	Options            SensorOptions
+	Sles12             osResource
+	Sles15             osResource
+	Rhel7              osResource
+	Rhel8              osResource
+	Rhel9              osResource
+	Rhel10             osResource
+	Oracle7            osResource
+	Oracle8            osResource
+	Oracle9            osResource
+	Oracle10           osResource
+	Debian             osResource
+	Ubuntu             osResource
+	Centos8            osResource
+	Centos9            osResource
+	Centos10           osResource
+	Windows            osResource
+	ExclusionLabelSets []LabelSet
+	InclusionLabelSets []LabelSet
+	Rollout            Rollout
+	// InstallParams are the install params of an os key or family (excluding CID), e.g. rhel9 or
+	// ubuntu. Targets without install params use LinuxInstallParams or WindowsInstallParams.
+	InstallParams map[string]string
+	// Hooks are extra exec resources added to the resource groups, keyed by os key, family or platform.
+	Hooks map[string]Hooks
+	// Staging configures where and how the installers are staged on the VMs, keyed by platform.
+	Staging map[string]Staging
+	// Template replaces the built-in resource groups with a user supplied os policy assignment.
+	// It is a text/template rendered with the Policy.
+	Template string
 }
 
 func NewPolicy(
 	cid string,
 	linuxInstallParams string,
 	windowsInstallParams string,
+	options SensorOptions,
 	sensors []*sensor.Sensor,
 	inclusionLabels []string,
 	exclusionLabels []string,
@@ -65,8 +96,11 @@ func NewPolicy(
 	var policy Policy
 
 	policy.Cid = cid
-	policy.LinuxInstallParams = formatLinuxArgs(cid, linuxInstallParams)
-	policy.WindowsInstallParams = formatWinArgs(cid, windowsInstallParams)
+	policy.Mode = ModeEnforcement
+	policy.Rollout = RolloutProfiles[DefaultRolloutProfile]
+	policy.Options = options
+	policy.LinuxInstallParams = formatLinuxArgs(cid, options, linuxInstallParams)
+	policy.WindowsInstallParams = formatWinArgs(cid, options, windowsInstallParams)
 
 	osVersionToField := map[string]*osResource{
 		"sles12*":   &policy.Sles12,
@@ -90,72 +124,418 @@ func NewPolicy(
 	for _, s := range sensors {
 		key := s.OsShortName + s.OsVersion
 		if r, ok := osVersionToField[key]; ok {
-			fpSplit := strings.Split(s.FullPath, "/")
-			*r = osResource{
-				Bucket:     strings.Join(fpSplit[:len(fpSplit)-1], "/"),
-				Object:     fpSplit[len(fpSplit)-1],
-				Generation: s.Generation,
+			*r = newOSResource(s.FullPath, s.Generation)
+
+			if s.SensorInfo.Version != nil {
+				r.Version = *s.SensorInfo.Version
 			}
 		}
 	}
 
-	var inclusionLabelSets []LabelSet
-	var exclusionLabelSets []LabelSet
+	policy.InclusionLabelSets = ParseLabelSets(inclusionLabels)
+	policy.ExclusionLabelSets = ParseLabelSets(exclusionLabels)
 
-	for _, label := range inclusionLabels {
-		parts := strings.Split(label, ":")
-		switch len(parts) {
-		case 1:
-			inclusionLabelSets = append(inclusionLabelSets, LabelSet{Label: parts[0]})
-		case 2:
-			inclusionLabelSets = append(
-				inclusionLabelSets,
-				LabelSet{Label: parts[0], Value: parts[1]},
-			)
-		}
+	return policy
+}
+
+// newOSResource splits the bucket/object path of a staged file.
+func newOSResource(fullPath string, generation int64) osResource {
+	bucket, object := path.Split(fullPath)
+	return osResource{
+		Bucket:     strings.TrimSuffix(bucket, "/"),
+		Object:     object,
+		Generation: generation,
 	}
+}
 
-	for _, label := range exclusionLabels {
-		parts := strings.Split(label, ":")
-		switch len(parts) {
-		case 1:
-			exclusionLabelSets = append(exclusionLabelSets, LabelSet{Label: parts[0]})
-		case 2:
-			exclusionLabelSets = append(
-				exclusionLabelSets,
-				LabelSet{Label: parts[0], Value: parts[1]},
-			)
+// SetSigningKey sets the bucket/object path and generation of the staged CrowdStrike signing key.
+func (p *Policy) SetSigningKey(fullPath string, generation int64) {
+	p.SigningKey = newOSResource(fullPath, generation)
+}
+
+// ParseLabelSets parses label sets in the format labelName:labelValue,labelName:labelValue.
+func ParseLabelSets(labelSets []string) []LabelSet {
+	var sets []LabelSet
+
+	for _, labelSet := range labelSets {
+		labels := map[string]string{}
+		for _, label := range strings.Split(labelSet, ",") {
+			name, value, _ := strings.Cut(strings.TrimSpace(label), ":")
+			if name != "" {
+				labels[name] = value
+			}
+		}
+
+		if len(labels) > 0 {
+			sets = append(sets, LabelSet{Labels: labels})
 		}
 	}
 
-	return policy
+	return sets
 }
 
-func (p Policy) GeneratePolicy(wr io.Writer) error {
-	funcMap := template.FuncMap{
-		"escapeJSON": escapeJSON,
+// InstanceFilter returns the VMs the assignment applies to, every VM when there are no label sets.
+func (p Policy) InstanceFilter() InstanceFilter {
+	filter := InstanceFilter{
+		InclusionLabels: p.InclusionLabelSets,
+		ExclusionLabels: p.ExclusionLabelSets,
 	}
+	filter.All = len(filter.InclusionLabels) == 0 && len(filter.ExclusionLabels) == 0
+	return filter
+}
 
-	t, err := template.New("policy").Funcs(funcMap).Parse(policyTemplate)
+// GeneratePolicy validates the os policy assignment and writes it to wr in the given format.
+func (p Policy) GeneratePolicy(wr io.Writer, format string) error {
+	a, err := p.OSPolicyAssignment()
 	if err != nil {
 		return err
 	}
 
-	return t.Execute(wr, p)
+	if err := a.Validate(); err != nil {
+		return err
+	}
+
+	if format == FormatYAML {
+		return writeYAML(wr, a)
+	}
+
+	enc := json.NewEncoder(wr)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	return enc.Encode(a)
 }
 
-// escapeJSON escapes backslashes for JSON strings only on Windows
-func escapeJSON(s string) string {
-	if runtime.GOOS == "windows" {
-		return strings.ReplaceAll(s, `\`, `\\`)
+// OSPolicyAssignment builds the os policy assignment that installs the sensor on every supported os,
+// or removes it when Uninstall is set.
+//
+// When a custom Template is set it is rendered instead of the built-in resource groups.
+func (p Policy) OSPolicyAssignment() (OSPolicyAssignment, error) {
+	if p.Template != "" {
+		return p.renderTemplate()
+	}
+
+	groups, err := p.resourceGroups()
+	if err != nil {
+		return OSPolicyAssignment{}, err
+	}
+
+	filter := p.InstanceFilter()
+
+	id := PolicyID
+	if p.Uninstall {
+		id = UninstallPolicyID
 	}
-	return s
+
+	return OSPolicyAssignment{
+		OSPolicies: []OSPolicy{
+			{
+				ID:             id,
+				Mode:           p.Mode,
+				ResourceGroups: groups,
+			},
+		},
+		InstanceFilter: filter,
+		Rollout:        p.Rollout,
+	}, nil
+}
+
+const (
+	PlatformLinux   = "linux"
+	PlatformWindows = "windows"
+)
+
+const (
+	installerRpm = iota
+	installerDeb
+	installerZypper
+	installerWindows
+)
+
+// osTarget is an os the policy installs the sensor on. Each target is a resource group in the policy.
+type osTarget struct {
+	// ID prefixes the ids of the resources in the group, e.g. rhel9.
+	ID string
+	// ConfigureID overrides the id prefix of the configure resource to stay compatible with
+	// existing assignments.
+	ConfigureID string
+	// Family is the os short name matched by the inventory filter, e.g. rhel.
+	Family    string
+	Version   string
+	Platform  string
+	Installer int
+	resource  func(p Policy) osResource
+}
+
+// Key returns the os short name and major version, e.g. rhel9. Targets without a version use the family.
+func (t osTarget) Key() string {
+	return t.Family + strings.TrimSuffix(t.Version, "*")
+}
+
+// familyAliases are the names used in resource ids for os families whose short names differ.
+var familyAliases = map[string]string{
+	"ol":   "oracle",
+	"sles": "suse",
+}
+
+// keys returns the keys the target can be configured by from most to least specific:
+// the os key (ol9) and the resource id (oracle9), the family (ol) and its alias (oracle), and the
+// platform (linux).
+func (t osTarget) keys() []string {
+	var keys []string
+	for _, key := range []string{t.Key(), t.ID, t.Family, familyAliases[t.Family], t.Platform} {
+		if key == "" {
+			continue
+		}
+
+		if !slices.Contains(keys, key) {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// OSKeys returns the platforms, os families and os keys that hooks and install params can be keyed by.
+func OSKeys() []string {
+	keys := []string{PlatformLinux, PlatformWindows}
+	for _, t := range osTargets {
+		for _, key := range t.keys() {
+			if !slices.Contains(keys, key) {
+				keys = append(keys, key)
+			}
+		}
+	}
+	return keys
+}
+
+func (t osTarget) configureID() string {
+	if t.ConfigureID != "" {
+		return t.ConfigureID
+	}
+	return t.ID
+}
+
+var osTargets = []osTarget{
+	{ID: "suse12", ConfigureID: "sles12", Family: "sles", Version: "12*", Platform: PlatformLinux, Installer: installerZypper, resource: func(p Policy) osResource { return p.Sles12 }},
+	{ID: "suse15", ConfigureID: "sles15", Family: "sles", Version: "15*", Platform: PlatformLinux, Installer: installerZypper, resource: func(p Policy) osResource { return p.Sles15 }},
+	{ID: "rhel7", Family: "rhel", Version: "7*", Platform: PlatformLinux, Installer: installerRpm, resource: func(p Policy) osResource { return p.Rhel7 }},
+	{ID: "rhel8", Family: "rhel", Version: "8*", Platform: PlatformLinux, Installer: installerRpm, resource: func(p Policy) osResource { return p.Rhel8 }},
+	{ID: "rhel9", Family: "rhel", Version: "9*", Platform: PlatformLinux, Installer: installerRpm, resource: func(p Policy) osResource { return p.Rhel9 }},
+	{ID: "rhel10", Family: "rhel", Version: "10*", Platform: PlatformLinux, Installer: installerRpm, resource: func(p Policy) osResource { return p.Rhel10 }},
+	{ID: "debian", Family: "debian", Platform: PlatformLinux, Installer: installerDeb, resource: func(p Policy) osResource { return p.Debian }},
+	{ID: "ubuntu", Family: "ubuntu", Platform: PlatformLinux, Installer: installerDeb, resource: func(p Policy) osResource { return p.Ubuntu }},
+	{ID: "centos8", Family: "centos", Version: "8*", Platform: PlatformLinux, Installer: installerRpm, resource: func(p Policy) osResource { return p.Centos8 }},
+	{ID: "centos9", Family: "centos", Version: "9*", Platform: PlatformLinux, Installer: installerRpm, resource: func(p Policy) osResource { return p.Centos9 }},
+	{ID: "centos10", Family: "centos", Version: "10*", Platform: PlatformLinux, Installer: installerRpm, resource: func(p Policy) osResource { return p.Centos10 }},
+	{ID: "oracle7", Family: "ol", Version: "7*", Platform: PlatformLinux, Installer: installerRpm, resource: func(p Policy) osResource { return p.Oracle7 }},
+	{ID: "oracle8", Family: "ol", Version: "8*", Platform: PlatformLinux, Installer: installerRpm, resource: func(p Policy) osResource { return p.Oracle8 }},
+	{ID: "oracle9", Family: "ol", Version: "9*", Platform: PlatformLinux, Installer: installerRpm, resource: func(p Policy) osResource { return p.Oracle9 }},
+	{ID: "oracle10", Family: "ol", Version: "10*", Platform: PlatformLinux, Installer: installerRpm, resource: func(p Policy) osResource { return p.Oracle10 }},
+	{ID: "windows", Family: "windows", Platform: PlatformWindows, Installer: installerWindows, resource: func(p Policy) osResource { return p.Windows }},
+}
+
+func (p Policy) resourceGroups() ([]ResourceGroup, error) {
+	var groups []ResourceGroup
+
+	for _, platform := range []string{PlatformLinux, PlatformWindows} {
+		if err := p.staging(platform).validate(platform); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := p.validateMaintenanceToken(); err != nil {
+		return nil, err
+	}
+
+	for _, t := range osTargets {
+		if p.Uninstall {
+			groups = append(groups, p.resourceGroup(t))
+			continue
+		}
+
+		if p.EnforceVersion && t.resource(p).Version == "" {
+			return nil, fmt.Errorf("the staged sensor version for %s is unknown, it is required to enforce the version", t.Key())
+		}
+
+		group, err := p.withHooks(t, p.resourceGroup(t))
+		if err != nil {
+			return nil, err
+		}
+		groups = append(groups, group)
+	}
+
+	return groups, nil
+}
+
+func (p Policy) resourceGroup(t osTarget) ResourceGroup {
+	var resources []Resource
+
+	switch {
+	case p.Uninstall:
+		resources = p.uninstallResources(t)
+	case p.EnforceVersion && t.Platform == PlatformLinux:
+		resources = p.upgradeResources(t)
+	case p.verifiesSignature(t):
+		resources = p.signedResources(t)
+	case t.Installer == installerZypper:
+		resources = p.zypperResources(t)
+	case t.Installer == installerRpm, t.Installer == installerDeb:
+		resources = p.pkgResources(t)
+	case t.Installer == installerWindows:
+		resources = p.windowsResources(t)
+	}
+
+	if !p.Uninstall {
+		resources = append(resources, healthResource(t))
+	}
+
+	return ResourceGroup{
+		InventoryFilters: []InventoryFilter{{OSShortName: t.Family, OSVersion: t.Version}},
+		Resources:        resources,
+	}
+}
+
+// zypperResources stages the rpm and installs it with zypper since the os policy agent
+// does not support installing rpms on SUSE.
+func (p Policy) zypperResources(t osTarget) []Resource {
+	return append(p.stagingResources(t),
+		Resource{
+			ID: t.ID + "-install",
+			Exec: &ExecResource{
+				Validate: Exec{Script: rpmQueryScript, Interpreter: InterpreterShell},
+				Enforce: &Exec{
+					Script:      p.linuxStageScript(t) + p.installCommand(t) + "\n" + rpmQueryScript,
+					Interpreter: InterpreterShell,
+				},
+			},
+		},
+		p.linuxConfigure(t, false),
+	)
+}
+
+func (p Policy) pkgResources(t osTarget) []Resource {
+	pkg := &PkgResource{DesiredState: PackageInstalled}
+	source := &PackageSource{Source: t.resource(p).file(), PullDeps: true}
+	if t.Installer == installerDeb {
+		pkg.Deb = source
+	} else {
+		pkg.Rpm = source
+	}
+
+	return []Resource{
+		{ID: t.ID + "-install", Pkg: pkg},
+		p.linuxConfigure(t, t.Installer == installerDeb),
+	}
+}
+
+func (p Policy) linuxConfigure(t osTarget, posixTest bool) Resource {
+	return Resource{
+		ID: t.configureID() + "-configure",
+		Exec: &ExecResource{
+			Validate: Exec{Script: linuxValidateScript, Interpreter: InterpreterShell},
+			Enforce: &Exec{
+				Script:      linuxConfigureScript(p.installParams(t), p.Options.secretArgs(), posixTest),
+				Interpreter: InterpreterShell,
+			},
+		},
+	}
+}
+
+// healthResource reports a sensor that has not registered or is in Reduced Functionality Mode. It
+// is separate from the configure resource so an unhealthy sensor is never reconfigured, its
+// enforce only waits for the sensor to register.
+func healthResource(t osTarget) Resource {
+	validate, enforce, interpreter := linuxHealthValidateScript, linuxHealthEnforceScript, InterpreterShell
+	if t.Platform == PlatformWindows {
+		validate, enforce, interpreter = windowsHealthValidateScript, windowsHealthEnforceScript, InterpreterPowerShell
+	}
+
+	return Resource{
+		ID: t.configureID() + "-health",
+		Exec: &ExecResource{
+			Validate: Exec{Script: validate, Interpreter: interpreter},
+			Enforce:  &Exec{Script: enforce, Interpreter: interpreter},
+		},
+	}
+}
+
+func (p Policy) windowsResources(t osTarget) []Resource {
+	validate := windowsValidateScript
+	maintenanceToken := ""
+	if p.EnforceVersion {
+		validate = windowsVersionScript(t.resource(p).Version)
+		maintenanceToken = p.windowsMaintenanceTokenScript("installArguments")
+	}
+
+	return append(p.stagingResources(t),
+		Resource{
+			ID: t.ID + "-install",
+			Exec: &ExecResource{
+				Validate: Exec{Script: validate, Interpreter: InterpreterPowerShell},
+				Enforce: &Exec{
+					Script: p.windowsStageScript(t) + windowsInstallScript(
+						p.installerPath(t),
+						p.installParams(t),
+						p.Options.secretArgs(),
+						maintenanceToken,
+						p.windowsCleanupScript(t),
+					),
+					Interpreter: InterpreterPowerShell,
+				},
+			},
+		},
+	)
+}
+
+// installParams returns the formatted install params of the target, falling back to the
+// platform-wide install params.
+func (p Policy) installParams(t osTarget) string {
+	for _, key := range t.keys() {
+		if key == t.Platform {
+			break
+		}
+
+		if params, ok := p.InstallParams[key]; ok {
+			if t.Platform == PlatformWindows {
+				return formatWinArgs(p.Cid, p.Options, params)
+			}
+			return formatLinuxArgs(p.Cid, p.Options, params)
+		}
+	}
+
+	if t.Platform == PlatformWindows {
+		return p.WindowsInstallParams
+	}
+	return p.LinuxInstallParams
+}
+
+// InstallParamsFor returns the formatted install params of an os key (e.g. rhel9) or family for use
+// in custom templates.
+func (p Policy) InstallParamsFor(key string) string {
+	for _, t := range osTargets {
+		if key != t.Platform && slices.Contains(t.keys(), key) {
+			return p.installParams(t)
+		}
+	}
+
+	if key == PlatformWindows {
+		return p.WindowsInstallParams
+	}
+	return p.LinuxInstallParams
+}
+
+func (r osResource) file() File {
+	return File{Gcs: &GcsObject{Bucket: r.Bucket, Object: r.Object, Generation: r.Generation}}
 }
 
 type Assignment struct {
-	Zone               string
+	// Project is the gcp project to create the assignment in. The gcloud cli's active project is used when empty.
+	Project string
+	Zone    string
+	// NamePrefix is prepended to the zone to name the assignment. Defaults to DeployAssignmentPrefix.
+	NamePrefix         string
 	PolicyTemplatePath string
 	SkipWait           bool
+	Logger             *slog.Logger
 
 	lock   sync.RWMutex
 	done   bool
@@ -178,6 +558,15 @@ func (a *Assignment) Done() bool {
 	return a.done
 }
 
+// Name returns the name of the os policy assignment in the zone.
+func (a *Assignment) Name() string {
+	prefix := a.NamePrefix
+	if prefix == "" {
+		prefix = DeployAssignmentPrefix
+	}
+	return fmt.Sprintf("%s-%s", prefix, a.Zone)
+}
+
 func (a *Assignment) RollOut(ctx context.Context) error {
 	gcloudPath, err := exec.LookPath("gcloud")
 	if err != nil {
@@ -189,15 +578,22 @@ func (a *Assignment) RollOut(ctx context.Context) error {
 		"os-config",
 		"os-policy-assignments",
 		"create",
-		fmt.Sprintf("crowdstrike-sensor-deploy-%s", a.Zone),
+		a.Name(),
 		fmt.Sprintf("--file=%s", a.PolicyTemplatePath),
 		fmt.Sprintf("--location=%s", a.Zone),
 	}
 
+	if a.Project != "" {
+		args = append(args, fmt.Sprintf("--project=%s", a.Project))
+	}
+
 	if a.SkipWait {
 		args = append(args, "--async")
 	}
 
+	logger := logging.Or(a.Logger).With("project", a.Project, "zone", a.Zone)
+	logger.Debug("creating os policy assignment", "gcloud", gcloudPath, "args", args)
+
 	bufErr := new(bytes.Buffer)
 
 	cmd := exec.CommandContext(ctx, gcloudPath, args...)
@@ -214,38 +610,43 @@ func (a *Assignment) RollOut(ctx context.Context) error {
 
 	if err != nil {
 		if strings.Contains(bufErr.String(), "ALREADY_EXISTS: Requested entity already exists") {
+			logger.Info("os policy assignment already exists")
 			return nil
 		}
 
+		logger.Debug("gcloud returned an error", "stderr", bufErr.String())
 		a.failed = true
 		return errors.New(bufErr.String())
 	}
 
+	logger.Info("os policy assignment created")
 	return nil
 }
 
-func formatWinArgs(cid string, args string) string {
-	params := fmt.Sprintf("'/install', '/quiet', '/norestart', 'CID=%s'", cid)
-
-	if len(args) > 0 {
-		sArgs := strings.Split(args, " ")
-		for i, arg := range sArgs {
-			if !strings.HasPrefix(arg, "'") {
-				sArgs[i] = "'" + arg
-			}
+// formatWinArgs returns the installer arguments as a PowerShell array of single quoted strings.
+func formatWinArgs(cid string, options SensorOptions, args string) string {
+	var params []string
+	for _, arg := range append([]string{"/install", "/quiet", "/norestart", "CID=" + cid}, options.WindowsArgs()...) {
+		params = append(params, powershellQuote(arg))
+	}
 
-			if !strings.HasSuffix(arg, "'") {
-				sArgs[i] = sArgs[i] + "'"
-			}
+	for _, arg := range splitArgs(args) {
+		// arguments the user already quoted are passed through as is.
+		if len(arg) > 1 && strings.HasPrefix(arg, "'") && strings.HasSuffix(arg, "'") {
+			params = append(params, arg)
+			continue
 		}
-
-		params = fmt.Sprintf("%s, %s", params, strings.Join(sArgs, ", "))
+		params = append(params, powershellQuote(arg))
 	}
 
-	return strings.ReplaceAll(params, "\"", "\\\"")
+	return strings.Join(params, ", ")
 }
 
-func formatLinuxArgs(cid string, args string) string {
-	params := fmt.Sprintf("--cid=%s %s", cid, args)
-	return strings.ReplaceAll(params, "\"", "\\\"")
+// formatLinuxArgs returns the falconctl arguments. User supplied args are passed through as is.
+func formatLinuxArgs(cid string, options SensorOptions, args string) string {
+	params := append([]string{"--cid=" + cid}, options.LinuxArgs()...)
+	if args = strings.TrimSpace(args); args != "" {
+		params = append(params, args)
+	}
+	return strings.Join(params, " ")
 }
@@ -0,0 +1,161 @@
+package policy
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/crowdstrike/gcp-os-policy/internal/sensor"
+	"github.com/crowdstrike/gofalcon/falcon/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stagedSensors returns a staged sensor for every os the policy supports.
+func stagedSensors() []*sensor.Sensor {
+	var sensors []*sensor.Sensor
+	version := "7.10.17706"
+	for i, os := range []struct{ name, version string }{
+		{"sles", "12*"}, {"sles", "15*"},
+		{"rhel", "7*"}, {"rhel", "8*"}, {"rhel", "9*"}, {"rhel", "10*"},
+		{"ol", "7*"}, {"ol", "8*"}, {"ol", "9*"}, {"ol", "10*"},
+		{"centos", "8*"}, {"centos", "9*"}, {"centos", "10*"},
+		{"debian", ""}, {"ubuntu", ""}, {"windows", ""},
+	} {
+		sensors = append(sensors, &sensor.Sensor{
+			OsShortName: os.name,
+			OsVersion:   os.version,
+			FullPath:    "bucket/" + os.name + "/sensor",
+			Generation:  int64(i + 1),
+			SensorInfo:  models.DomainSensorInstallerV1{Version: &version},
+		})
+	}
+	return sensors
+}
+
+func findResource(t *testing.T, a OSPolicyAssignment, id string) *Resource {
+	t.Helper()
+	for _, g := range a.OSPolicies[0].ResourceGroups {
+		for i := range g.Resources {
+			if g.Resources[i].ID == id {
+				return &g.Resources[i]
+			}
+		}
+	}
+	t.Fatalf("resource %s not found", id)
+	return nil
+}
+
+func TestGeneratePolicy_EscapesInstallParams(t *testing.T) {
+	p := NewPolicy(
+		"cid",
+		`--tags="a b" --aph=proxy\host`,
+		`GROUPING_TAGS="a,b" PROXYHOST=C:\proxy`,
+		SensorOptions{},
+		append(
+			stagedSensors(),
+			&sensor.Sensor{OsShortName: "windows", FullPath: `bucket/windows\sensor.exe`, Generation: 42},
+		),
+		nil,
+		nil,
+	)
+
+	var buf bytes.Buffer
+	require.NoError(t, p.GeneratePolicy(&buf, FormatJSON))
+
+	var got OSPolicyAssignment
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &got))
+
+	configure := findResource(t, got, "rhel9-configure")
+	assert.True(
+		t,
+		strings.HasPrefix(configure.Exec.Enforce.Script, `/opt/CrowdStrike/falconctl -sf --cid=cid --tags="a b" --aph=proxy\host`),
+	)
+
+	install := findResource(t, got, "windows-install")
+	assert.Contains(t, install.Exec.Enforce.Script, `'GROUPING_TAGS="a,b"', 'PROXYHOST=C:\proxy'`)
+
+	stage := findResource(t, got, "windows-stage-installer")
+	assert.Equal(t, GcsObject{Bucket: "bucket", Object: `windows\sensor.exe`, Generation: 42}, *stage.File.File.Gcs)
+	assert.Contains(t, buf.String(), `"generation": "42"`)
+}
+
+func TestOSPolicyAssignment_Rollout(t *testing.T) {
+	p := NewPolicy("cid", "", "", SensorOptions{}, nil, nil, nil)
+	p.Rollout = Rollout{DisruptionBudget: DisruptionBudget{Fixed: 5}, MinWaitDuration: "60s"}
+
+	a, err := p.OSPolicyAssignment()
+	require.NoError(t, err)
+
+	b, err := json.Marshal(a.Rollout)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"disruptionBudget": {"fixed": 5}, "minWaitDuration": "60s"}`, string(b))
+}
+
+func TestOSPolicyAssignment_InstanceFilter(t *testing.T) {
+	tests := []struct {
+		name      string
+		inclusion []string
+		exclusion []string
+		want      InstanceFilter
+	}{
+		{name: "all", want: InstanceFilter{All: true}},
+		{
+			name:      "labels",
+			inclusion: []string{"env:prod,team:sec"},
+			exclusion: []string{"falcon"},
+			want: InstanceFilter{
+				InclusionLabels: []LabelSet{{Labels: map[string]string{"env": "prod", "team": "sec"}}},
+				ExclusionLabels: []LabelSet{{Labels: map[string]string{"falcon": ""}}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewPolicy("cid", "", "", SensorOptions{}, nil, tt.inclusion, tt.exclusion)
+			a, err := p.OSPolicyAssignment()
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, a.InstanceFilter)
+		})
+	}
+}
+
+func TestInstallParams(t *testing.T) {
+	p := NewPolicy("cid", "--tags=linux", "GROUPING_TAGS=windows", SensorOptions{}, stagedSensors(), nil, nil)
+	p.InstallParams = map[string]string{
+		"rhel9":   "--tags=rhel9 --aph=proxy.example.com",
+		"ol":      "--tags=oracle",
+		"oracle9": "--tags=oracle9",
+		"suse":    "--tags=suse",
+		"ubuntu":  "--tags=ubuntu",
+	}
+
+	a, err := p.OSPolicyAssignment()
+	require.NoError(t, err)
+
+	tests := []struct {
+		id   string
+		want string
+	}{
+		{id: "rhel9-configure", want: "-sf --cid=cid --tags=rhel9 --aph=proxy.example.com\n"},
+		{id: "rhel8-configure", want: "-sf --cid=cid --tags=linux\n"},
+		{id: "oracle8-configure", want: "-sf --cid=cid --tags=oracle\n"},
+		{id: "oracle9-configure", want: "-sf --cid=cid --tags=oracle9\n"},
+		{id: "sles15-configure", want: "-sf --cid=cid --tags=suse\n"},
+		{id: "ubuntu-configure", want: "-sf --cid=cid --tags=ubuntu\n"},
+		{id: "windows-install", want: "'CID=cid', 'GROUPING_TAGS=windows')"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.id, func(t *testing.T) {
+			assert.Contains(t, findResource(t, a, tt.id).Exec.Enforce.Script, tt.want)
+		})
+	}
+
+	assert.Equal(t, "--cid=cid --tags=rhel9 --aph=proxy.example.com", p.InstallParamsFor("rhel9"))
+	assert.Equal(t, "--cid=cid --tags=suse", p.InstallParamsFor("sles"))
+	assert.Equal(t, "--cid=cid --tags=oracle", p.InstallParamsFor("oracle8"))
+	assert.Equal(t, "--cid=cid --tags=linux", p.InstallParamsFor("debian"))
+}
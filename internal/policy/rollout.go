@@ -0,0 +1,158 @@
+package policy
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultRolloutProfile matches the historical behavior of updating every VM in a zone at once.
+const DefaultRolloutProfile = "immediate"
+
+// DisruptionBudget is the maximum number (Fixed) or percentage (Percent) of VMs in a zone
+// that may be disrupted at once during a rollout. Only one of the fields is set.
+type DisruptionBudget struct {
+	Fixed   int `json:"fixed,omitempty"`
+	Percent int `json:"percent,omitempty"`
+}
+
+// String returns the budget as accepted by ParseDisruptionBudget.
+func (b DisruptionBudget) String() string {
+	if b.Fixed > 0 {
+		return strconv.Itoa(b.Fixed)
+	}
+	return fmt.Sprintf("%d%%", b.Percent)
+}
+
+// Rollout controls the pace at which an assignment is applied to the VMs in a zone.
+type Rollout struct {
+	DisruptionBudget DisruptionBudget `json:"disruptionBudget"`
+	// MinWaitDuration is a duration in the format GCP accepts, e.g. 300s.
+	MinWaitDuration string `json:"minWaitDuration"`
+}
+
+// RolloutProfiles are named rollout defaults that can be overridden by individual flags.
+var RolloutProfiles = map[string]Rollout{
+	"immediate": {
+		DisruptionBudget: DisruptionBudget{Percent: 100},
+		MinWaitDuration:  "0s",
+	},
+	"standard": {
+		DisruptionBudget: DisruptionBudget{Percent: 25},
+		MinWaitDuration:  "300s",
+	},
+	"conservative": {
+		DisruptionBudget: DisruptionBudget{Percent: 10},
+		MinWaitDuration:  "900s",
+	},
+}
+
+// RolloutProfileNames returns the names of the rollout profiles sorted alphabetically.
+func RolloutProfileNames() []string {
+	var names []string
+	for name := range RolloutProfiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewRollout starts from the named profile and applies the disruption budget and minimum wait
+// duration overrides when they are not empty.
+func NewRollout(profile string, disruptionBudget string, minWaitDuration string) (Rollout, error) {
+	if profile == "" {
+		profile = DefaultRolloutProfile
+	}
+
+	rollout, ok := RolloutProfiles[profile]
+	if !ok {
+		return Rollout{}, fmt.Errorf(
+			"unknown rollout profile %q: must be one of %s",
+			profile,
+			strings.Join(RolloutProfileNames(), ", "),
+		)
+	}
+
+	if disruptionBudget != "" {
+		b, err := ParseDisruptionBudget(disruptionBudget)
+		if err != nil {
+			return Rollout{}, err
+		}
+		rollout.DisruptionBudget = b
+	}
+
+	if minWaitDuration != "" {
+		d, err := ParseMinWaitDuration(minWaitDuration)
+		if err != nil {
+			return Rollout{}, err
+		}
+		rollout.MinWaitDuration = d
+	}
+
+	return rollout, nil
+}
+
+// ParseDisruptionBudget parses a percentage (25%) or a fixed number of VMs (10).
+func ParseDisruptionBudget(s string) (DisruptionBudget, error) {
+	s = strings.TrimSpace(s)
+
+	if p, ok := strings.CutSuffix(s, "%"); ok {
+		percent, err := strconv.Atoi(p)
+		if err != nil || percent < 1 || percent > 100 {
+			return DisruptionBudget{}, fmt.Errorf(
+				"invalid disruption budget %q: percent must be between 1%% and 100%%",
+				s,
+			)
+		}
+		return DisruptionBudget{Percent: percent}, nil
+	}
+
+	fixed, err := strconv.Atoi(s)
+	if err != nil || fixed < 1 {
+		return DisruptionBudget{}, fmt.Errorf(
+			"invalid disruption budget %q: must be a percentage (25%%) or a number of VMs of at least 1",
+			s,
+		)
+	}
+
+	return DisruptionBudget{Fixed: fixed}, nil
+}
+
+// ParseMinWaitDuration parses a duration such as 300s, 5m or 1h30m and returns it in
+// the seconds based format GCP accepts.
+func ParseMinWaitDuration(s string) (string, error) {
+	d, err := time.ParseDuration(strings.TrimSpace(s))
+	if err != nil {
+		return "", fmt.Errorf("invalid minimum wait duration %q: %w", s, err)
+	}
+
+	if d < 0 {
+		return "", fmt.Errorf("invalid minimum wait duration %q: must not be negative", s)
+	}
+
+	return strconv.FormatFloat(d.Seconds(), 'f', -1, 64) + "s", nil
+}
+
+// Describe returns a human readable summary of the rollout pacing.
+func (r Rollout) Describe() string {
+	var budget string
+	switch {
+	case r.DisruptionBudget.Fixed == 1:
+		budget = "1 VM"
+	case r.DisruptionBudget.Fixed > 1:
+		budget = fmt.Sprintf("%d VMs", r.DisruptionBudget.Fixed)
+	case r.DisruptionBudget.Percent >= 100:
+		budget = "all VMs"
+	default:
+		budget = fmt.Sprintf("%d%% of VMs", r.DisruptionBudget.Percent)
+	}
+
+	wait := "without waiting between batches"
+	if d, err := time.ParseDuration(r.MinWaitDuration); err == nil && d > 0 {
+		wait = fmt.Sprintf("waiting at least %s between batches", d)
+	}
+
+	return fmt.Sprintf("Updating %s per zone at a time, %s.", budget, wait)
+}
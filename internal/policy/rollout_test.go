@@ -0,0 +1,99 @@
+package policy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseDisruptionBudget(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    DisruptionBudget
+		wantErr bool
+	}{
+		{input: "100%", want: DisruptionBudget{Percent: 100}},
+		{input: "1%", want: DisruptionBudget{Percent: 1}},
+		{input: " 25% ", want: DisruptionBudget{Percent: 25}},
+		{input: "10", want: DisruptionBudget{Fixed: 10}},
+		{input: "0%", wantErr: true},
+		{input: "101%", wantErr: true},
+		{input: "0", wantErr: true},
+		{input: "-5", wantErr: true},
+		{input: "ten", wantErr: true},
+		{input: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseDisruptionBudget(tt.input)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestParseMinWaitDuration(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{input: "0s", want: "0s"},
+		{input: "300s", want: "300s"},
+		{input: "5m", want: "300s"},
+		{input: "1h30m", want: "5400s"},
+		{input: "1.5s", want: "1.5s"},
+		{input: "-1m", wantErr: true},
+		{input: "300", wantErr: true},
+		{input: "soon", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseMinWaitDuration(tt.input)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestNewRollout(t *testing.T) {
+	r, err := NewRollout("", "", "")
+	assert.NoError(t, err)
+	assert.Equal(t, RolloutProfiles[DefaultRolloutProfile], r)
+
+	r, err = NewRollout("conservative", "5", "")
+	assert.NoError(t, err)
+	assert.Equal(t, DisruptionBudget{Fixed: 5}, r.DisruptionBudget)
+	assert.Equal(t, "900s", r.MinWaitDuration)
+
+	_, err = NewRollout("reckless", "", "")
+	assert.Error(t, err)
+}
+
+func TestRollout_Describe(t *testing.T) {
+	assert.Equal(
+		t,
+		"Updating all VMs per zone at a time, without waiting between batches.",
+		RolloutProfiles["immediate"].Describe(),
+	)
+	assert.Equal(
+		t,
+		"Updating 25% of VMs per zone at a time, waiting at least 5m0s between batches.",
+		RolloutProfiles["standard"].Describe(),
+	)
+	assert.Equal(
+		t,
+		"Updating 1 VM per zone at a time, waiting at least 1m30s between batches.",
+		Rollout{DisruptionBudget: DisruptionBudget{Fixed: 1}, MinWaitDuration: "90s"}.Describe(),
+	)
+}
@@ -0,0 +1,284 @@
+package policy
+
+import "strings"
+
+const alreadyInstalledMessage = "Falcon Sensor already installed... if you want to update or downgrade, please use Sensor Update Policies in the CrowdStrike console. Please see: https://falcon.crowdstrike.com/documentation/66/sensor-update-policies for more information."
+
+const rpmQueryScript = "/usr/bin/rpmquery -q falcon-sensor && exit 100 || exit 101\n"
+
+// linuxAidScript reads the sensor's AID, it is empty until the sensor has registered with the
+// CrowdStrike cloud.
+const linuxAidScript = `aid=$(/opt/CrowdStrike/falconctl -g --aid 2>/dev/null | sed -nE 's/.*aid="?([0-9a-fA-F]+)"?.*/\1/p')
+`
+
+// linuxWaitForAidScript gives a freshly started sensor a minute to register.
+const linuxWaitForAidScript = `for i in 1 2 3 4 5 6 7 8 9 10 11 12; do
+  ` + linuxAidScript + `  if [ -n "${aid}" ]; then
+    break
+  fi
+  sleep 5
+done
+`
+
+// linuxHealthScript exits 101 with the reason when a running sensor has not registered or is in
+// Reduced Functionality Mode, the output is shown in the OS Config reports.
+const linuxHealthScript = `if [ -z "${aid}" ]; then
+  echo "Falcon Sensor is running but has not registered with the CrowdStrike cloud, no AID is set"
+  exit 101
+fi
+if /opt/CrowdStrike/falconctl -g --rfm-state 2>/dev/null | grep -q 'rfm-state=true'; then
+  echo "Falcon Sensor ${aid} is running in Reduced Functionality Mode"
+  exit 101
+fi
+`
+
+const linuxValidateScript = `if ! pgrep  -u root falcon-sensor >/dev/null 2>&1 ; then
+  exit 101
+fi
+echo "` + alreadyInstalledMessage + `"
+exit 100
+`
+
+// linuxRunningScript exits 101 when the sensor is not running.
+const linuxRunningScript = `if ! pgrep  -u root falcon-sensor >/dev/null 2>&1 ; then
+  echo "Falcon Sensor is not running"
+  exit 101
+fi
+`
+
+// linuxHealthValidateScript exits 100 when the running sensor has registered and is not in
+// Reduced Functionality Mode.
+const linuxHealthValidateScript = linuxRunningScript + linuxAidScript + linuxHealthScript + `echo "Falcon Sensor ${aid} is registered with the CrowdStrike cloud"
+exit 100
+`
+
+// linuxHealthEnforceScript waits for the sensor to register without changing its configuration,
+// a sensor that stays unhealthy is reported as failed.
+const linuxHealthEnforceScript = linuxRunningScript + linuxWaitForAidScript + linuxHealthScript + "exit 100\n"
+
+// linuxVersionScript exits 100 when the installed sensor is at least the staged version.
+//
+// Package versions look like 7.10.0-17706.el9 while the Falcon API reports 7.10.17706, so the
+// major, minor and build numbers are extracted before comparing.
+func linuxVersionScript(query string, version string) string {
+	return `staged=` + shellQuote(version) + `
+installed=$(` + query + ` 2>/dev/null | sed -nE 's/^([0-9]+)\.([0-9]+)\.[0-9]+-([0-9]+).*/\1.\2.\3/p')
+if [ -z "${installed}" ]; then
+  echo "Falcon Sensor is not installed"
+  exit 101
+fi
+if [ "$(printf '%s\n%s\n' "${staged}" "${installed}" | sort -V | head -n 1)" = "${staged}" ]; then
+  echo "Falcon Sensor ${installed} is up to date with ${staged}"
+  exit 100
+fi
+echo "Falcon Sensor ${installed} is older than ${staged}"
+exit 101
+`
+}
+
+// windowsVersionScript exits 100 when the installed sensor's file version is at least the staged version.
+func windowsVersionScript(version string) string {
+	return `$staged = [version]` + powershellQuote(version) + `
+$service = Get-Item "$env:ProgramFiles\CrowdStrike\CSFalconService.exe" -ErrorAction SilentlyContinue
+if (-not $service) {
+    Write-Output 'Falcon Sensor is not installed'
+    Exit 101
+}
+$installed = [version]$service.VersionInfo.FileVersion
+if ($installed -lt $staged) {
+    Write-Output "Falcon Sensor $installed is older than $staged"
+    Exit 101
+}
+Write-Output "Falcon Sensor $installed is up to date with $staged"
+Exit 100
+`
+}
+
+// windowsAgentIDKey is the registry key of the sensor's AG value, the binary AID of the host.
+const windowsAgentIDKey = `HKLM:\SYSTEM\CrowdStrike\{9b03c1d9-3138-44ed-9fae-d9f4c034b88d}\{16e0423f-7058-48c9-a204-725362b67639}\Default`
+
+// windowsAidScript reads the sensor's AG value, it is missing until the sensor has registered
+// with the CrowdStrike cloud.
+const windowsAidScript = `$agentId = (Get-ItemProperty -Path '` + windowsAgentIDKey + `' -Name AG -ErrorAction SilentlyContinue).AG
+`
+
+// windowsHealthScript exits 101 with the reason when the sensor has not registered, see linuxHealthScript.
+const windowsHealthScript = `if (-not $agentId) {
+    Write-Output 'Falcon Sensor is running but has not registered with the CrowdStrike cloud, no AID is set'
+    Exit 101
+}
+`
+
+const windowsValidateScript = `$agentService = Get-Service -Name CSAgent -ErrorAction SilentlyContinue
+if (-not $agentService) {
+    Exit 101
+}
+Write-Output '` + alreadyInstalledMessage + `'
+Exit 100
+`
+
+// windowsWaitForAidScript gives a freshly started sensor a minute to register.
+const windowsWaitForAidScript = `for ($i = 0; $i -lt 12; $i++) {
+    ` + windowsAidScript + `    if ($agentId) {
+        break
+    }
+    Start-Sleep -Seconds 5
+}
+`
+
+// windowsRunningScript exits 101 when the CSAgent service is not running.
+const windowsRunningScript = `$agentService = Get-Service -Name CSAgent -ErrorAction SilentlyContinue
+if (-not $agentService -or $agentService.Status -ne 'Running') {
+    Write-Output 'Falcon Sensor is not running'
+    Exit 101
+}
+`
+
+// windowsHealthValidateScript exits 100 when the running sensor has registered, see linuxHealthValidateScript.
+const windowsHealthValidateScript = windowsRunningScript + windowsAidScript + windowsHealthScript + `Write-Output 'Falcon Sensor is registered with the CrowdStrike cloud'
+Exit 100
+`
+
+// windowsHealthEnforceScript waits for the sensor to register, see linuxHealthEnforceScript.
+const windowsHealthEnforceScript = windowsRunningScript + windowsWaitForAidScript + windowsHealthScript + "Exit 100\n"
+
+const metadataTokenURL = "http://metadata.google.internal/computeMetadata/v1/instance/service-accounts/default/token"
+
+const secretManagerURL = "https://secretmanager.googleapis.com/v1/"
+
+// linuxMetadataTokenScript sets metadata_token to the VM service account's access token.
+const linuxMetadataTokenScript = `metadata_token=$(curl -sSf -H "Metadata-Flavor: Google" "` + metadataTokenURL + `" | sed -E 's/.*"access_token": *"([^"]+)".*/\1/')
+if [ -z "${metadata_token}" ]; then
+  echo "Unable to get an access token from the metadata server"
+  exit 101
+fi
+`
+
+// windowsMetadataTokenScript sets $metadataToken to the VM service account's access token.
+const windowsMetadataTokenScript = `try {
+    $metadataToken = (Invoke-RestMethod -UseBasicParsing -Headers @{'Metadata-Flavor' = 'Google'} -Uri '` + metadataTokenURL + `').access_token
+}
+catch {
+    Write-Output 'Unable to get an access token from the metadata server'
+    Exit 101
+}
+`
+
+// linuxSecretsScript reads the secrets into shell variables using the VM service account's
+// access token from the metadata server. Values are never echoed so they do not end up in the
+// os policy agent's logs.
+func linuxSecretsScript(secrets []secretArg) string {
+	if len(secrets) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString(linuxMetadataTokenScript)
+
+	for _, s := range secrets {
+		b.WriteString(linuxReadSecretScript(s.Secret, s.Variable))
+	}
+
+	b.WriteString("unset metadata_token\n")
+	return b.String()
+}
+
+// linuxReadSecretScript reads the secret version into variable with ${metadata_token}.
+func linuxReadSecretScript(secret string, variable string) string {
+	return variable + `=$(curl -sSf -H "Authorization: Bearer ${metadata_token}" "` + secretManagerURL + secret + `:access" | tr -d '\n' | sed -E 's/.*"data": *"([^"]+)".*/\1/' | base64 -d)
+if [ -z "${` + variable + `}" ]; then
+  echo "Unable to read secret ` + secret + `"
+  exit 101
+fi
+`
+}
+
+// linuxConfigureScript sets the sensor's install params and starts the sensor.
+//
+// The os policy agent runs scripts with /bin/sh, so dash based distributions must use
+// the POSIX test builtin instead of [[.
+func linuxConfigureScript(installParams string, secrets []secretArg, posixTest bool) string {
+	test := `[[ -L "/sbin/init" ]]`
+	if posixTest {
+		test = `[ -L "/sbin/init" ]`
+	}
+
+	for _, s := range secrets {
+		installParams += " " + s.LinuxFlag + `="${` + s.Variable + `}"`
+	}
+
+	return linuxSecretsScript(secrets) + "/opt/CrowdStrike/falconctl -sf " + installParams + `
+if ` + test + `
+then
+    systemctl start falcon-sensor
+else
+    sudo service falcon-sensor start
+fi
+if ! pgrep  -u root falcon-sensor >/dev/null 2>&1 ; then
+  echo "Falcon Sensor failed to start"
+  exit 101
+fi
+` + linuxWaitForAidScript + linuxHealthScript + `echo "Falcon Sensor ${aid} is running and registered"
+exit 100
+`
+}
+
+// windowsSecretsScript appends the secrets to the installer arguments using the VM service
+// account's access token from the metadata server.
+func windowsSecretsScript(secrets []secretArg) string {
+	if len(secrets) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString(windowsMetadataTokenScript)
+
+	for _, s := range secrets {
+		b.WriteString(windowsReadSecretScript(s.Secret, s.Variable))
+		b.WriteString(`$installArguments += "` + s.WindowsArg + `=$` + s.Variable + `"
+`)
+	}
+
+	b.WriteString("Remove-Variable metadataToken\n")
+	return b.String()
+}
+
+// windowsReadSecretScript reads the secret version into $variable with $metadataToken.
+func windowsReadSecretScript(secret string, variable string) string {
+	return `try {
+    $secret = Invoke-RestMethod -UseBasicParsing -Headers @{Authorization = "Bearer $metadataToken"} -Uri '` + secretManagerURL + secret + `:access'
+    $` + variable + ` = [System.Text.Encoding]::UTF8.GetString([System.Convert]::FromBase64String($secret.payload.data))
+}
+catch {
+    Write-Output 'Unable to read secret ` + secret + `'
+    Exit 101
+}
+`
+}
+
+// windowsInstallScript runs the installer staged at installer. maintenanceToken is the script
+// that adds the maintenance token to $installArguments when upgrading protected sensors, cleanup
+// is the script that removes the installer once it has exited.
+func windowsInstallScript(installer string, installParams string, secrets []secretArg, maintenanceToken string, cleanup string) string {
+	return "$installArguments = @(" + installParams + ")\n" + windowsSecretsScript(secrets) + maintenanceToken + `$installerProcess = Start-Process -FilePath ` + powershellQuote(installer) + ` -ArgumentList $installArguments -PassThru -Wait
+` + cleanup + `
+
+if ($installerProcess.ExitCode -ne 0) {
+    Write-Output "Installer returned exit code $($installerProcess.ExitCode)"
+    Exit 101
+}
+
+$agentService = Get-Service -Name CSAgent -ErrorAction SilentlyContinue
+if (-not $agentService) {
+    Write-Output 'Installer completed, but CSAgent service is missing...'
+    Exit 101
+}
+elseif ($agentService.Status -ne 'Running') {
+    Write-Output 'Installer completed, but CSAgent service is not running...'
+    Exit 101
+}
+
+Write-Output 'CSAgent service running...'
+` + windowsWaitForAidScript + windowsHealthScript + `Exit 100
+`
+}
@@ -0,0 +1,147 @@
+package policy
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testSecret = "projects/example/secrets/falcon-token/versions/latest"
+
+// fakeCurl responds like the metadata server and Secret Manager. The secret payload is
+// "abcd1234" base64 encoded.
+const fakeCurl = `#!/bin/sh
+for arg in "$@"; do url="$arg"; done
+case "$url" in
+  *computeMetadata*)
+    echo '{"access_token":"ya29.test","expires_in":3599,"token_type":"Bearer"}' ;;
+  *secretmanager*)
+    if [ "$FAIL_SECRET" = "1" ]; then exit 22; fi
+    printf '{\n  "name": "%s",\n  "payload": {\n    "data": "YWJjZDEyMzQ=",\n    "dataCrc32c": "1"\n  }\n}\n' "$url" ;;
+esac
+`
+
+func TestLinuxSecretsScript(t *testing.T) {
+	for _, bin := range []string{"sh", "sed", "tr", "base64"} {
+		if _, err := exec.LookPath(bin); err != nil {
+			t.Skipf("%s is required: %s", bin, err)
+		}
+	}
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "curl"), []byte(fakeCurl), 0o755))
+
+	secrets := SensorOptions{ProvisioningTokenSecret: testSecret}.secretArgs()
+	script := linuxSecretsScript(secrets) + `printf '%s' "${FALCON_PROVISIONING_TOKEN}"` + "\n"
+
+	run := func(env ...string) (string, error) {
+		cmd := exec.Command("sh", "-c", script)
+		cmd.Env = append(os.Environ(), append(env, "PATH="+dir+":"+os.Getenv("PATH"))...)
+		out, err := cmd.Output()
+		return string(out), err
+	}
+
+	out, err := run()
+	require.NoError(t, err)
+	assert.Equal(t, "abcd1234", out)
+
+	out, err = run("FAIL_SECRET=1")
+	var exitErr *exec.ExitError
+	require.ErrorAs(t, err, &exitErr)
+	assert.Equal(t, 101, exitErr.ExitCode())
+	assert.Contains(t, out, "Unable to read secret "+testSecret)
+}
+
+func TestSecretScripts(t *testing.T) {
+	p := NewPolicy(
+		"cid",
+		"",
+		"",
+		SensorOptions{ProvisioningTokenSecret: "projects/example/secrets/falcon-token"},
+		stagedSensors(),
+		nil,
+		nil,
+	)
+
+	a, err := p.OSPolicyAssignment()
+	require.NoError(t, err)
+	require.NoError(t, a.Validate())
+
+	linux := findResource(t, a, "rhel9-configure").Exec.Enforce.Script
+	assert.Contains(t, linux, secretManagerURL+testSecret+":access")
+	assert.Contains(t, linux, `/opt/CrowdStrike/falconctl -sf --cid=cid --provisioning-token="${FALCON_PROVISIONING_TOKEN}"`)
+	assert.Less(t, strings.Index(linux, "FALCON_PROVISIONING_TOKEN=$("), strings.Index(linux, "falconctl -sf"))
+
+	windows := findResource(t, a, "windows-install").Exec.Enforce.Script
+	assert.Contains(t, windows, "-Uri '"+secretManagerURL+testSecret+":access'")
+	assert.Contains(t, windows, `$installArguments += "ProvToken=$FALCON_PROVISIONING_TOKEN"`)
+	assert.Less(t, strings.Index(windows, "$installArguments +="), strings.Index(windows, "Start-Process"))
+
+	if _, err := exec.LookPath("sh"); err == nil {
+		out, err := exec.Command("sh", "-n", "-c", linux).CombinedOutput()
+		assert.NoError(t, err, string(out))
+	}
+}
+
+// fakeFalconctl reports the AID and rfm state from $AID and $RFM.
+const fakeFalconctl = `#!/bin/sh
+case "$2" in
+  --aid) if [ -n "$AID" ]; then echo "aid=\"$AID\"."; else echo "aid is not set."; fi ;;
+  --rfm-state) echo "rfm-state=${RFM:-false}." ;;
+esac
+`
+
+func TestLinuxHealthScript(t *testing.T) {
+	for _, bin := range []string{"sh", "sed", "grep"} {
+		if _, err := exec.LookPath(bin); err != nil {
+			t.Skipf("%s is required: %s", bin, err)
+		}
+	}
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "falconctl"), []byte(fakeFalconctl), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "pgrep"), []byte("#!/bin/sh\n[ \"$RUNNING\" = \"1\" ]\n"), 0o755))
+	falconctl := filepath.Join(dir, "falconctl")
+
+	// the configure resource only checks that the sensor is running so an unhealthy sensor is
+	// never reconfigured, the health resource reports it.
+	tests := []struct {
+		name      string
+		env       []string
+		configure int
+		exitCode  int
+		output    string
+	}{
+		{"not running", nil, 101, 101, "Falcon Sensor is not running"},
+		{"not registered", []string{"RUNNING=1"}, 100, 101, "has not registered"},
+		{"reduced functionality mode", []string{"RUNNING=1", "AID=0123abcd", "RFM=true"}, 100, 101, "Falcon Sensor 0123abcd is running in Reduced Functionality Mode"},
+		{"healthy", []string{"RUNNING=1", "AID=0123abcd"}, 100, 100, "Falcon Sensor 0123abcd is registered"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			run := func(script string) (int, string) {
+				cmd := exec.Command("sh", "-c", strings.ReplaceAll(script, "/opt/CrowdStrike/falconctl", falconctl))
+				cmd.Env = append(os.Environ(), append(tt.env, "PATH="+dir+":"+os.Getenv("PATH"))...)
+				out, err := cmd.Output()
+
+				var exitErr *exec.ExitError
+				require.ErrorAs(t, err, &exitErr)
+				return exitErr.ExitCode(), string(out)
+			}
+
+			code, _ := run(linuxValidateScript)
+			assert.Equal(t, tt.configure, code)
+
+			code, out := run(linuxHealthValidateScript)
+			assert.Equal(t, tt.exitCode, code)
+			assert.Contains(t, out, tt.output)
+		})
+	}
+
+	assert.NotContains(t, linuxHealthEnforceScript, "-sf")
+}
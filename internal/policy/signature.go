@@ -0,0 +1,142 @@
+package policy
+
+// verifySignatures returns true when the Linux installers are verified with the SigningKey before
+// they are installed.
+func (p Policy) verifySignatures() bool {
+	return p.SigningKey.Object != ""
+}
+
+// verifiesSignature returns true when the installer of the target is verified before it is
+// installed.
+func (p Policy) verifiesSignature(t osTarget) bool {
+	return p.verifySignatures() && t.Platform == PlatformLinux
+}
+
+// installCommand installs the staged installer with the package manager of the target.
+// zypper only skips the signature check when signatures are not verified.
+func (p Policy) installCommand(t osTarget) string {
+	installer := shellQuote(p.installerPath(t))
+
+	switch t.Installer {
+	case installerDeb:
+		return "sudo DEBIAN_FRONTEND=noninteractive apt-get install -y " + installer
+	case installerZypper:
+		if p.verifiesSignature(t) {
+			return "sudo zypper -n install " + installer
+		}
+		return "sudo zypper -n --no-gpg-checks install " + installer
+	}
+	return "sudo yum -y install " + installer
+}
+
+// installedQuery succeeds when the sensor package is installed.
+func installedQuery(t osTarget) string {
+	if t.Installer == installerDeb {
+		return debInstalledQuery
+	}
+	return rpmInstalledQuery
+}
+
+// signedResources stages the installer and the signing key, and installs the sensor once the
+// installer's signature has been verified.
+func (p Policy) signedResources(t osTarget) []Resource {
+	installed := `if ` + installedQuery(t) + ` ; then
+  exit 100
+fi
+exit 101
+`
+
+	return append(p.stagingResources(t),
+		Resource{
+			ID: t.ID + "-install",
+			Exec: &ExecResource{
+				Validate: Exec{Script: installed, Interpreter: InterpreterShell},
+				Enforce: &Exec{
+					Script:      p.linuxStageScript(t) + p.verifySignatureScript(t) + p.installCommand(t) + "\n" + installed,
+					Interpreter: InterpreterShell,
+				},
+			},
+		},
+		p.linuxConfigure(t, t.Installer == installerDeb),
+	)
+}
+
+const signatureErrorMessage = "The Falcon Sensor installer signature could not be verified with the CrowdStrike signing key"
+
+// rpmSignatureKeyQuery prints the signature of an rpm, e.g.
+// RSA/SHA256, Mon 01 Jan 2024 00:00:00 AM UTC, Key ID 0123456789abcdef.
+const rpmSignatureKeyQuery = `'%|RSAHEADER?{%{RSAHEADER:pgpsig}}:{%|DSAHEADER?{%{DSAHEADER:pgpsig}}:{%|SIGPGP?{%{SIGPGP:pgpsig}}:{%{SIGGPG:pgpsig}}|}|}|\n'`
+
+// verifySignatureScript exits 101 unless the staged installer is signed by the signing key.
+//
+// Unsigned rpms pass rpm --checksig, so the output must include a signature check, and rpms
+// signed by any other key in the rpmdb pass it too, so the key ID of the signature must be one of
+// the staged key's IDs.
+func (p Policy) verifySignatureScript(t osTarget) string {
+	installer := shellQuote(p.installerPath(t))
+	signingKey := shellQuote(p.stagedPath(t, signingKeyFile))
+
+	if t.Installer == installerDeb {
+		return debSignatureScript(installer, signingKey)
+	}
+
+	return `sudo rpm --import ` + signingKey + `
+if ! signature=$(rpm --checksig ` + installer + `) || ! echo "${signature}" | grep -qiE 'pgp|signatures' ; then
+  echo "` + signatureErrorMessage + `"
+  exit 101
+fi
+GNUPGHOME=$(mktemp -d)
+export GNUPGHOME
+keys=$(gpg --batch --with-colons --show-keys ` + signingKey + ` 2>/dev/null || gpg --batch --with-colons ` + signingKey + ` 2>/dev/null)
+rm -rf "${GNUPGHOME}"
+unset GNUPGHOME
+keyids=$(echo "${keys}" | grep -E '^(pub|sub):' | cut -d: -f5 | tr 'A-F' 'a-f')
+keyid=$(rpm -qp --qf ` + rpmSignatureKeyQuery + ` ` + installer + ` 2>/dev/null | sed -nE 's/.*Key ID ([0-9a-fA-F]+).*/\1/p' | tr 'A-F' 'a-f')
+if [ -z "${keyids}" ] || [ -z "${keyid}" ] || ! echo "${keyids}" | grep -qx "${keyid}" ; then
+  echo "` + signatureErrorMessage + `, it is signed by key ${keyid:-none}"
+  exit 101
+fi
+`
+}
+
+// debSignatureScript exits 101 unless the deb is signed by the signing key. The debs are signed
+// with dpkg-sig, which adds a clearsigned _gpgbuilder member listing the sha1 and size of every
+// other member. dpkg-sig is not on stock images, so the ar archive is read with tail and head,
+// the signature is checked with gpgv, which apt depends on, against the dearmored signing key and
+// every member must be listed in the signed output.
+func debSignatureScript(installer string, signingKey string) string {
+	return `workdir=$(mktemp -d)
+if ! (
+  cd "${workdir}" || exit 1
+  tr -d '\r' < ` + signingKey + ` |
+    sed -e '/^-----BEGIN PGP PUBLIC KEY BLOCK-----$/,/^-----END PGP PUBLIC KEY BLOCK-----$/!d' \
+      -e '/^-----/d' -e '/^[A-Za-z-]*: /d' -e '/^=/d' -e '/^[[:space:]]*$/d' |
+    base64 -d > key.gpg || exit 1
+  offset=8
+  while header=$(tail -c +$((offset + 1)) ` + installer + ` | head -c 60) && [ -n "${header}" ]; do
+    name=$(printf '%s' "${header}" | cut -c1-16 | tr -d ' ' | sed 's|/$||')
+    size=$(printf '%s' "${header}" | cut -c49-58 | tr -d ' ')
+    case "${size}" in
+      '' | *[!0-9]*) exit 1 ;;
+    esac
+    if [ "${name}" = "_gpgbuilder" ]; then
+      tail -c +$((offset + 61)) ` + installer + ` | head -c "${size}" > signature
+    else
+      echo "$(tail -c +$((offset + 61)) ` + installer + ` | head -c "${size}" | sha1sum | cut -d ' ' -f 1) ${size} ${name}" >> members
+    fi
+    offset=$((offset + 60 + size + size % 2))
+  done
+  [ -s signature ] && [ -s members ] || exit 1
+  GNUPGHOME="${workdir}" gpgv --keyring "${workdir}/key.gpg" --output signed signature >/dev/null 2>&1 || exit 1
+  sed -n 's/^[[:space:]]*[0-9a-f]\{32\} \([0-9a-f]\{40\} [0-9]* [^ ]*\)$/\1/p' signed > signed-members
+  while read -r member; do
+    grep -qxF "${member}" signed-members || exit 1
+  done < members
+); then
+  rm -rf "${workdir}"
+  echo "` + signatureErrorMessage + `"
+  exit 101
+fi
+rm -rf "${workdir}"
+`
+}
@@ -0,0 +1,259 @@
+package policy
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/sha1"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func signedPolicy() Policy {
+	p := NewPolicy("cid", "", "", SensorOptions{}, stagedSensors(), nil, nil)
+	p.SetSigningKey("bucket/crowdstrike/falcon/signing-keys/abc.asc", 7)
+	return p
+}
+
+func TestSignedResources(t *testing.T) {
+	p := signedPolicy()
+	assert.Equal(t, osResource{Bucket: "bucket/crowdstrike/falcon/signing-keys", Object: "abc.asc", Generation: 7}, p.SigningKey)
+
+	a, err := p.OSPolicyAssignment()
+	require.NoError(t, err)
+	require.NoError(t, a.Validate())
+
+	tests := []struct {
+		id        string
+		installer string
+		contains  []string
+	}{
+		{"rhel9", "/tmp/falcon-sensor.rpm", []string{"rpm --import /tmp/falcon-sensor.asc", "rpm --checksig /tmp/falcon-sensor.rpm", "yum -y install"}},
+		{"suse15", "/tmp/falcon-sensor.rpm", []string{"rpm --checksig /tmp/falcon-sensor.rpm", "zypper -n install"}},
+		{"ubuntu", "/tmp/falcon-sensor.deb", []string{"gpgv --keyring", "apt-get install -y /tmp/falcon-sensor.deb"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.id, func(t *testing.T) {
+			assert.Equal(t, tt.installer, findResource(t, a, tt.id+"-stage-installer").File.Path)
+			assert.Equal(t, "abc.asc", findResource(t, a, tt.id+"-stage-signing-key").File.File.Gcs.Object)
+
+			enforce := findResource(t, a, tt.id+"-install").Exec.Enforce.Script
+			for _, s := range tt.contains {
+				assert.Contains(t, enforce, s)
+			}
+			assert.NotContains(t, enforce, "--no-gpg-checks")
+			assert.Less(t, strings.Index(enforce, signatureErrorMessage), strings.Index(enforce, tt.contains[len(tt.contains)-1]))
+		})
+	}
+
+	assert.Equal(t, []string{"rhel9-stage-installer", "rhel9-stage-signing-key", "rhel9-install", "rhel9-configure", "rhel9-health"}, resourceIDs(a, "rhel", "9*"))
+	assert.Equal(t, []string{"windows-stage-installer", "windows-install", "windows-health"}, resourceIDs(a, "windows", ""))
+	assert.Equal(
+		t,
+		[]string{"ubuntu-stage-installer", "ubuntu-stage-signing-key", "ubuntu-install", "ubuntu-configure", "ubuntu-health"},
+		resourceIDs(a, "ubuntu", ""),
+	)
+
+	p.EnforceVersion = true
+	a, err = p.OSPolicyAssignment()
+	require.NoError(t, err)
+	assert.Contains(t, findResource(t, a, "rhel9-install").Exec.Enforce.Script, "rpm --checksig")
+	findResource(t, a, "rhel9-stage-signing-key")
+	assert.Contains(t, findResource(t, a, "ubuntu-install").Exec.Enforce.Script, signatureErrorMessage)
+}
+
+func TestLegacyResources(t *testing.T) {
+	a, err := NewPolicy("cid", "", "", SensorOptions{}, stagedSensors(), nil, nil).OSPolicyAssignment()
+	require.NoError(t, err)
+
+	assert.NotNil(t, findResource(t, a, "rhel9-install").Pkg)
+	assert.Contains(t, findResource(t, a, "suse15-install").Exec.Enforce.Script, "--no-gpg-checks")
+	assert.NotContains(t, resourceIDs(a, "rhel", "9*"), "rhel9-stage-signing-key")
+}
+
+// fakeRpmChecksig prints $CHECKSIG for rpm --checksig and fails when $CHECKSIG is NOT OK, and
+// prints a signature by $SIGNED_BY for rpm -qp.
+const fakeRpmChecksig = `#!/bin/sh
+case "$1" in
+--checksig)
+  echo "/tmp/falcon-sensor.rpm: $CHECKSIG"
+  case "$CHECKSIG" in *"NOT OK"*) exit 1 ;; esac
+  ;;
+-qp)
+  if [ -n "$SIGNED_BY" ]; then
+    echo "RSA/SHA256, Mon 01 Jan 2024 00:00:00 AM UTC, Key ID $SIGNED_BY"
+  else
+    echo "(none)"
+  fi
+  ;;
+esac
+`
+
+// fakeGpg lists the staged signing key with the 0123456789ABCDEF primary key and the
+// FEDCBA9876543210 subkey.
+const fakeGpg = `#!/bin/sh
+echo "pub:-:4096:1:0123456789ABCDEF:1700000000:::-:::scESC::::::23::0:"
+echo "fpr:::::::::AAAAAAAAAAAAAAAAAAAAAAAA0123456789ABCDEF:"
+echo "sub:-:4096:1:FEDCBA9876543210:1700000000::::::s::::::23:"
+`
+
+func TestRpmSignatureScript(t *testing.T) {
+	for _, bin := range []string{"sh", "grep", "sed", "cut", "tr", "mktemp"} {
+		if _, err := exec.LookPath(bin); err != nil {
+			t.Skipf("%s is required: %s", bin, err)
+		}
+	}
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "rpm"), []byte(fakeRpmChecksig), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "gpg"), []byte(fakeGpg), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "sudo"), []byte("#!/bin/sh\nexec \"$@\"\n"), 0o755))
+	script := Policy{}.verifySignatureScript(osTarget{Platform: PlatformLinux, Installer: installerRpm}) + "exit 100\n"
+
+	tests := []struct {
+		name     string
+		checksig string
+		signedBy string
+		exitCode int
+	}{
+		{"signed", "digests signatures OK", "0123456789abcdef", 100},
+		{"legacy rpm", "rsa sha1 (md5) pgp md5 OK", "0123456789abcdef", 100},
+		{"signed by subkey", "digests signatures OK", "FEDCBA9876543210", 100},
+		{"unsigned", "digests OK", "", 101},
+		{"legacy unsigned", "sha1 md5 OK", "", 101},
+		{"bad signature", "digests SIGNATURES NOT OK", "0123456789abcdef", 101},
+		{"signed by another key", "digests signatures OK", "1111111111111111", 101},
+		{"no signature key", "digests signatures OK", "", 101},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := exec.Command("sh", "-c", script)
+			cmd.Env = append(os.Environ(), "CHECKSIG="+tt.checksig, "SIGNED_BY="+tt.signedBy, "PATH="+dir+":"+os.Getenv("PATH"))
+
+			var exitErr *exec.ExitError
+			require.ErrorAs(t, cmd.Run(), &exitErr)
+			assert.Equal(t, tt.exitCode, exitErr.ExitCode())
+		})
+	}
+}
+
+// gpgTestKey generates a signing key in its own home directory and returns the home directory
+// and the ascii armored public key.
+func gpgTestKey(t *testing.T, uid string) (string, []byte) {
+	t.Helper()
+
+	home, err := os.MkdirTemp("", "gpg")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		exec.Command("gpgconf", "--homedir", home, "--kill", "gpg-agent").Run()
+		os.RemoveAll(home)
+	})
+
+	gpg := func(args ...string) []byte {
+		cmd := exec.Command("gpg", append([]string{"--batch", "--homedir", home, "--pinentry-mode", "loopback", "--passphrase", ""}, args...)...)
+		out, err := cmd.Output()
+		require.NoError(t, err)
+		return out
+	}
+
+	gpg("--quick-gen-key", uid, "ed25519", "sign", "never")
+	return home, gpg("--armor", "--export", uid)
+}
+
+// clearsign signs the message with the key in home.
+func clearsign(t *testing.T, home string, message string) []byte {
+	t.Helper()
+
+	cmd := exec.Command("gpg", "--batch", "--homedir", home, "--pinentry-mode", "loopback", "--passphrase", "", "--clearsign")
+	cmd.Stdin = strings.NewReader(message)
+	out, err := cmd.Output()
+	require.NoError(t, err)
+	return out
+}
+
+type arMember struct {
+	name string
+	data []byte
+}
+
+// arArchive builds a deb, an ar archive of the members.
+func arArchive(members []arMember) []byte {
+	var b bytes.Buffer
+	b.WriteString("!<arch>\n")
+	for _, m := range members {
+		fmt.Fprintf(&b, "%-16s%-12d%-6d%-6d%-8s%-10d`\n", m.name, 1700000000, 0, 0, "100644", len(m.data))
+		b.Write(m.data)
+		if len(m.data)%2 == 1 {
+			b.WriteString("\n")
+		}
+	}
+	return b.Bytes()
+}
+
+// dpkgSig returns the _gpgbuilder member dpkg-sig adds to sign the members.
+func dpkgSig(t *testing.T, home string, members []arMember) arMember {
+	t.Helper()
+
+	var files strings.Builder
+	for _, m := range members {
+		fmt.Fprintf(&files, "\t%x %x %d %s\n", md5.Sum(m.data), sha1.Sum(m.data), len(m.data), m.name)
+	}
+
+	message := "Version: 4\nSigner: \nDate: Mon Jan  1 00:00:00 2024\nRole: builder\nFiles: \n" + files.String()
+	return arMember{name: "_gpgbuilder", data: clearsign(t, home, message)}
+}
+
+func TestDebSignatureScript(t *testing.T) {
+	for _, bin := range []string{"sh", "gpg", "gpgconf", "gpgv", "tail", "head", "cut", "sed", "tr", "base64", "sha1sum", "mktemp", "grep"} {
+		if _, err := exec.LookPath(bin); err != nil {
+			t.Skipf("%s is required: %s", bin, err)
+		}
+	}
+
+	crowdstrike, key := gpgTestKey(t, "CrowdStrike Test <crowdstrike@example.com>")
+	other, _ := gpgTestKey(t, "Other <other@example.com>")
+
+	dir := t.TempDir()
+	keyPath := filepath.Join(dir, "falcon-sensor.asc")
+	require.NoError(t, os.WriteFile(keyPath, key, 0o644))
+
+	members := []arMember{
+		{name: "debian-binary", data: []byte("2.0\n")},
+		{name: "control.tar.gz", data: bytes.Repeat([]byte("c"), 101)},
+		{name: "data.tar.xz", data: bytes.Repeat([]byte("d"), 4096)},
+	}
+	tampered := slices.Clone(members)
+	tampered[2] = arMember{name: "data.tar.xz", data: bytes.Repeat([]byte("x"), 4096)}
+
+	tests := []struct {
+		name     string
+		deb      []byte
+		exitCode int
+	}{
+		{"signed", arArchive(append(slices.Clone(members), dpkgSig(t, crowdstrike, members))), 100},
+		{"unsigned", arArchive(members), 101},
+		{"signed by another key", arArchive(append(slices.Clone(members), dpkgSig(t, other, members))), 101},
+		{"tampered member", arArchive(append(slices.Clone(tampered), dpkgSig(t, crowdstrike, members))), 101},
+		{"unsigned member", arArchive(append(slices.Clone(members), dpkgSig(t, crowdstrike, members[:2]))), 101},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deb := filepath.Join(t.TempDir(), "falcon-sensor.deb")
+			require.NoError(t, os.WriteFile(deb, tt.deb, 0o644))
+
+			cmd := exec.Command("sh", "-c", debSignatureScript(shellQuote(deb), shellQuote(keyPath))+"exit 100\n")
+			out, err := cmd.Output()
+
+			var exitErr *exec.ExitError
+			require.ErrorAs(t, err, &exitErr)
+			assert.Equal(t, tt.exitCode, exitErr.ExitCode(), string(out))
+		})
+	}
+}
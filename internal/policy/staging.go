@@ -0,0 +1,226 @@
+package policy
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Staging configures how the installers are staged on the VMs of a platform.
+type Staging struct {
+	// Dir is the directory the installers are staged in, it must exist on the VMs.
+	Dir string `yaml:"dir"`
+	// Mode is the octal file mode of the staged installers, e.g. 750. The signing key is always 644.
+	Mode string `yaml:"mode"`
+	// Cleanup removes the installers once the install script has run. The install script then
+	// downloads the installers itself, a file resource would stage them again on every
+	// compliance check.
+	Cleanup bool `yaml:"cleanup"`
+}
+
+// DefaultStaging is the staging of each platform, configured staging overrides the set fields.
+var DefaultStaging = map[string]Staging{
+	PlatformLinux:   {Dir: "/tmp", Mode: "755"},
+	PlatformWindows: {Dir: `C:\Windows\SystemTemp`, Mode: "755"},
+}
+
+var (
+	// stagingDirPatterns limit staging directories to absolute paths that are safe to render
+	// into scripts.
+	stagingDirPatterns = map[string]*regexp.Regexp{
+		PlatformLinux:   regexp.MustCompile(`^/[A-Za-z0-9._/-]*$`),
+		PlatformWindows: regexp.MustCompile(`^[A-Za-z]:\\[A-Za-z0-9._\\ ()-]*$`),
+	}
+	modePattern = regexp.MustCompile(`^[0-7]{3,4}$`)
+)
+
+const storageURL = "https://storage.googleapis.com/"
+
+const signingKeyFile = "falcon-sensor.asc"
+
+// staging returns the staging of a platform with the unset fields defaulted.
+func (p Policy) staging(platform string) Staging {
+	s := DefaultStaging[platform]
+	configured := p.Staging[platform]
+
+	if configured.Dir != "" {
+		s.Dir = configured.Dir
+	}
+	if configured.Mode != "" {
+		s.Mode = configured.Mode
+	}
+	s.Cleanup = configured.Cleanup
+
+	return s
+}
+
+func (s Staging) validate(platform string) error {
+	if !stagingDirPatterns[platform].MatchString(s.Dir) {
+		return fmt.Errorf(
+			"invalid %s staging dir %q: must be an absolute path of letters, digits and . _ - characters",
+			platform,
+			s.Dir,
+		)
+	}
+
+	if !modePattern.MatchString(s.Mode) {
+		return fmt.Errorf("invalid %s staging mode %q: must be an octal file mode e.g. 755", platform, s.Mode)
+	}
+
+	return nil
+}
+
+// stagedPath returns the path of a file staged on the VMs of the target's platform.
+func (p Policy) stagedPath(t osTarget, name string) string {
+	dir := p.staging(t.Platform).Dir
+	if t.Platform == PlatformWindows {
+		return strings.TrimRight(dir, `\`) + `\` + name
+	}
+	return strings.TrimRight(dir, "/") + "/" + name
+}
+
+// installerPath returns where the installer of a target is staged.
+func (p Policy) installerPath(t osTarget) string {
+	switch t.Installer {
+	case installerDeb:
+		return p.stagedPath(t, "falcon-sensor.deb")
+	case installerWindows:
+		return p.stagedPath(t, "falcon-sensor.exe")
+	}
+	return p.stagedPath(t, "falcon-sensor.rpm")
+}
+
+// stagedFile is a file the install script of a target needs on the VM.
+type stagedFile struct {
+	// ID is the id of the file resource that stages the file.
+	ID     string
+	Path   string
+	Mode   string
+	Source osResource
+}
+
+// stagedFiles returns the installer of the target and, when Linux installers are verified, the
+// signing key.
+func (p Policy) stagedFiles(t osTarget) []stagedFile {
+	files := []stagedFile{
+		{
+			ID:     t.ID + "-stage-installer",
+			Path:   p.installerPath(t),
+			Mode:   p.staging(t.Platform).Mode,
+			Source: t.resource(p),
+		},
+	}
+
+	if p.verifiesSignature(t) {
+		files = append(files, stagedFile{
+			ID:     t.ID + "-stage-signing-key",
+			Path:   p.stagedPath(t, signingKeyFile),
+			Mode:   "644",
+			Source: p.SigningKey,
+		})
+	}
+
+	return files
+}
+
+// stagingResources stages the files of the target with file resources. There are none when the
+// installers are cleaned up, the install script downloads them instead.
+func (p Policy) stagingResources(t osTarget) []Resource {
+	if p.staging(t.Platform).Cleanup {
+		return nil
+	}
+
+	var resources []Resource
+	for _, f := range p.stagedFiles(t) {
+		resources = append(resources, Resource{
+			ID: f.ID,
+			File: &FileResource{
+				File:        f.Source.file(),
+				Path:        f.Path,
+				State:       FileContentsMatch,
+				Permissions: f.Mode,
+			},
+		})
+	}
+	return resources
+}
+
+// linuxStageScript downloads the files of the target with the VM service account's access token
+// and removes them when the script exits. It is empty unless the installers are cleaned up.
+func (p Policy) linuxStageScript(t osTarget) string {
+	if !p.staging(t.Platform).Cleanup {
+		return ""
+	}
+
+	files := p.stagedFiles(t)
+	paths := make([]string, 0, len(files))
+	for _, f := range files {
+		paths = append(paths, shellQuote(f.Path))
+	}
+
+	var b strings.Builder
+	b.WriteString(linuxMetadataTokenScript)
+	b.WriteString(`trap "rm -f ` + strings.Join(paths, " ") + `" EXIT
+`)
+
+	for _, f := range files {
+		b.WriteString(`if ! curl -sSf -H "Authorization: Bearer ${metadata_token}" -o ` + shellQuote(f.Path) + ` "` + f.Source.url() + `" ; then
+  echo "Unable to download ` + f.Source.gsPath() + `"
+  exit 101
+fi
+chmod ` + f.Mode + ` ` + shellQuote(f.Path) + `
+`)
+	}
+
+	b.WriteString("unset metadata_token\n")
+	return b.String()
+}
+
+// windowsStageScript downloads the installer, see linuxStageScript. It is removed by
+// windowsCleanupScript once the installer has exited.
+func (p Policy) windowsStageScript(t osTarget) string {
+	if !p.staging(t.Platform).Cleanup {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString(windowsMetadataTokenScript)
+
+	for _, f := range p.stagedFiles(t) {
+		b.WriteString(`try {
+    Invoke-WebRequest -UseBasicParsing -Headers @{Authorization = "Bearer $metadataToken"} -Uri '` + f.Source.url() + `' -OutFile ` + powershellQuote(f.Path) + `
+}
+catch {
+    Write-Output 'Unable to download ` + f.Source.gsPath() + `'
+    Exit 101
+}
+`)
+	}
+
+	b.WriteString("Remove-Variable metadataToken\n")
+	return b.String()
+}
+
+// windowsCleanupScript removes the downloaded installer. It is empty unless the installers are
+// cleaned up.
+func (p Policy) windowsCleanupScript(t osTarget) string {
+	if !p.staging(t.Platform).Cleanup {
+		return ""
+	}
+	return "Remove-Item -Path " + powershellQuote(p.installerPath(t)) + " -Force -ErrorAction SilentlyContinue\n"
+}
+
+// url returns the Cloud Storage url of the object's generation.
+func (r osResource) url() string {
+	segments := strings.Split(r.Bucket+"/"+r.Object, "/")
+	for i, s := range segments {
+		segments[i] = url.PathEscape(s)
+	}
+	return fmt.Sprintf("%s%s?generation=%d", storageURL, strings.Join(segments, "/"), r.Generation)
+}
+
+// gsPath returns the gs:// path of the object.
+func (r osResource) gsPath() string {
+	return "gs://" + r.Bucket + "/" + r.Object
+}
@@ -0,0 +1,118 @@
+package policy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStaging(t *testing.T) {
+	tests := []struct {
+		name    string
+		staging map[string]Staging
+		signed  bool
+		check   func(t *testing.T, a OSPolicyAssignment)
+		wantErr string
+	}{
+		{
+			name: "defaults",
+			check: func(t *testing.T, a OSPolicyAssignment) {
+				suse := findResource(t, a, "suse15-stage-installer").File
+				assert.Equal(t, "/tmp/falcon-sensor.rpm", suse.Path)
+				assert.Equal(t, "755", suse.Permissions)
+				assert.Contains(t, findResource(t, a, "suse15-install").Exec.Enforce.Script, "zypper -n --no-gpg-checks install /tmp/falcon-sensor.rpm")
+				assert.Equal(t, `C:\Windows\SystemTemp\falcon-sensor.exe`, findResource(t, a, "windows-stage-installer").File.Path)
+				assert.Contains(t, findResource(t, a, "windows-install").Exec.Enforce.Script, `-FilePath 'C:\Windows\SystemTemp\falcon-sensor.exe'`)
+			},
+		},
+		{
+			name: "custom dir and mode",
+			staging: map[string]Staging{
+				PlatformLinux:   {Dir: "/var/lib/falcon/", Mode: "0750"},
+				PlatformWindows: {Dir: `D:\Staging`},
+			},
+			signed: true,
+			check: func(t *testing.T, a OSPolicyAssignment) {
+				rhel := findResource(t, a, "rhel9-stage-installer").File
+				assert.Equal(t, "/var/lib/falcon/falcon-sensor.rpm", rhel.Path)
+				assert.Equal(t, "0750", rhel.Permissions)
+
+				key := findResource(t, a, "rhel9-stage-signing-key").File
+				assert.Equal(t, "/var/lib/falcon/falcon-sensor.asc", key.Path)
+				assert.Equal(t, "644", key.Permissions)
+
+				enforce := findResource(t, a, "rhel9-install").Exec.Enforce.Script
+				assert.Contains(t, enforce, "rpm --import /var/lib/falcon/falcon-sensor.asc")
+				assert.Contains(t, enforce, "yum -y install /var/lib/falcon/falcon-sensor.rpm")
+				assert.NotContains(t, enforce, "/tmp/")
+
+				assert.Equal(t, `D:\Staging\falcon-sensor.exe`, findResource(t, a, "windows-stage-installer").File.Path)
+				assert.Equal(t, "755", findResource(t, a, "windows-stage-installer").File.Permissions)
+			},
+		},
+		{
+			name: "cleanup",
+			staging: map[string]Staging{
+				PlatformLinux:   {Cleanup: true},
+				PlatformWindows: {Cleanup: true},
+			},
+			signed: true,
+			check: func(t *testing.T, a OSPolicyAssignment) {
+				assert.Equal(t, []string{"rhel9-install", "rhel9-configure", "rhel9-health"}, resourceIDs(a, "rhel", "9*"))
+				assert.Equal(t, []string{"windows-install", "windows-health"}, resourceIDs(a, "windows", ""))
+
+				enforce := findResource(t, a, "suse15-install").Exec.Enforce.Script
+				assert.Contains(t, enforce, `trap "rm -f /tmp/falcon-sensor.rpm /tmp/falcon-sensor.asc" EXIT`)
+				assert.Contains(t, enforce, "https://storage.googleapis.com/bucket/crowdstrike/falcon/signing-keys/abc.asc?generation=7")
+				assert.Contains(t, enforce, "chmod 644 /tmp/falcon-sensor.asc")
+				assert.Contains(t, enforce, "zypper -n install /tmp/falcon-sensor.rpm")
+
+				windows := findResource(t, a, "windows-install").Exec.Enforce.Script
+				assert.Contains(t, windows, `-OutFile 'C:\Windows\SystemTemp\falcon-sensor.exe'`)
+				assert.Contains(t, windows, `Remove-Item -Path 'C:\Windows\SystemTemp\falcon-sensor.exe'`)
+			},
+		},
+		{
+			name:    "relative dir",
+			staging: map[string]Staging{PlatformLinux: {Dir: "staging"}},
+			wantErr: `invalid linux staging dir "staging"`,
+		},
+		{
+			name:    "unsafe dir",
+			staging: map[string]Staging{PlatformLinux: {Dir: "/tmp/$(reboot)"}},
+			wantErr: "invalid linux staging dir",
+		},
+		{
+			name:    "linux dir on windows",
+			staging: map[string]Staging{PlatformWindows: {Dir: "/tmp"}},
+			wantErr: "invalid windows staging dir",
+		},
+		{
+			name:    "invalid mode",
+			staging: map[string]Staging{PlatformLinux: {Mode: "rwx"}},
+			wantErr: `invalid linux staging mode "rwx"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewPolicy("cid", "", "", SensorOptions{}, stagedSensors(), nil, nil)
+			if tt.signed {
+				p = signedPolicy()
+			}
+			p.Staging = tt.staging
+
+			a, err := p.OSPolicyAssignment()
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			require.NoError(t, a.Validate())
+			tt.check(t, a)
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package policy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+var templateFuncs = map[string]any{
+	// quote returns s as a double quoted string that is valid in both json and yaml documents.
+	"quote": func(s string) (string, error) {
+		b, err := json.Marshal(s)
+		return string(b), err
+	},
+}
+
+// renderTemplate renders the custom Template and decodes it into an os policy assignment.
+//
+// The template may be json or yaml. Fields that are not part of OSPolicyAssignment are
+// rejected rather than silently dropped from the generated policy.
+func (p Policy) renderTemplate() (OSPolicyAssignment, error) {
+	var a OSPolicyAssignment
+
+	rendered, err := p.render("template", p.Template)
+	if err != nil {
+		return a, err
+	}
+
+	// json is valid yaml, decode as yaml then round trip through json so the json struct tags apply.
+	var doc any
+	if err := yaml.Unmarshal([]byte(rendered), &doc); err != nil {
+		return a, fmt.Errorf("rendered template is not valid json or yaml: %w", err)
+	}
+
+	b, err := json.Marshal(doc)
+	if err != nil {
+		return a, fmt.Errorf("rendered template is not valid json or yaml: %w", err)
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&a); err != nil {
+		return a, fmt.Errorf("rendered template is not a supported os policy assignment: %w", err)
+	}
+
+	return a, nil
+}
@@ -0,0 +1,81 @@
+package policy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testTemplate = `
+osPolicies:
+  - id: custom
+    mode: {{ .Mode }}
+    resourceGroups:
+      - inventoryFilters:
+          - osShortName: rhel
+        resources:
+          - id: rhel-install
+            pkg:
+              desiredState: INSTALLED
+              rpm:
+                source:
+                  gcs:
+                    bucket: {{ .Rhel9.Bucket }}
+                    object: {{ .Rhel9.Object }}
+                    generation: {{ .Rhel9.Generation }}
+          - id: rhel-configure
+            exec:
+              validate:
+                interpreter: SHELL
+                script: "pgrep falcon-sensor && exit 100 || exit 101"
+              enforce:
+                interpreter: SHELL
+                script: {{ printf "/opt/CrowdStrike/falconctl -sf %s && exit 100" .LinuxInstallParams | quote }}
+instanceFilter:
+  all: true
+rollout:
+  disruptionBudget:
+    percent: 100
+  minWaitDuration: 0s
+`
+
+func TestRenderTemplate(t *testing.T) {
+	p := NewPolicy("cid", `--tags="a b"`, "", SensorOptions{}, stagedSensors(), nil, nil)
+	p.Template = testTemplate
+
+	a, err := p.OSPolicyAssignment()
+	require.NoError(t, err)
+	require.NoError(t, a.Validate())
+
+	assert.Equal(t, "custom", a.OSPolicies[0].ID)
+	assert.Equal(t, ModeEnforcement, a.OSPolicies[0].Mode)
+	assert.Equal(t, int64(5), findResource(t, a, "rhel-install").Pkg.Rpm.Source.Gcs.Generation)
+	assert.Equal(
+		t,
+		`/opt/CrowdStrike/falconctl -sf --cid=cid --tags="a b" && exit 100`,
+		findResource(t, a, "rhel-configure").Exec.Enforce.Script,
+	)
+}
+
+func TestRenderTemplate_Invalid(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		wantErr  string
+	}{
+		{name: "unknown field", template: `{"osPolicies": [], "description": "x"}`, wantErr: "not a supported os policy assignment"},
+		{name: "not yaml", template: `{"osPolicies": [`, wantErr: "not valid json or yaml"},
+		{name: "bad template", template: `{{ .Nope }}`, wantErr: "failed to render template"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewPolicy("cid", "", "", SensorOptions{}, stagedSensors(), nil, nil)
+			p.Template = tt.template
+
+			_, err := p.OSPolicyAssignment()
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
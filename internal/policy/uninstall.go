@@ -0,0 +1,102 @@
+package policy
+
+// UninstallPolicyID is the id of the os policy within decommission assignments.
+const UninstallPolicyID = "crowdstrike-falcon-sensor-decommission"
+
+const (
+	rpmInstalledQuery = "rpm -q falcon-sensor >/dev/null 2>&1"
+	debInstalledQuery = "dpkg-query -W -f='${Status}' falcon-sensor 2>/dev/null | grep -q 'ok installed'"
+)
+
+// uninstallResources stops and removes the sensor. Uninstall protected sensors require a
+// maintenance token.
+func (p Policy) uninstallResources(t osTarget) []Resource {
+	if t.Platform == PlatformWindows {
+		return []Resource{
+			{
+				ID: t.ID + "-uninstall",
+				Exec: &ExecResource{
+					Validate: Exec{Script: windowsUninstallValidateScript, Interpreter: InterpreterPowerShell},
+					Enforce:  &Exec{Script: windowsUninstallScript(p.windowsMaintenanceTokenScript("uninstallArguments")), Interpreter: InterpreterPowerShell},
+				},
+			},
+		}
+	}
+
+	installed := rpmInstalledQuery
+	remove := "sudo rpm -e falcon-sensor"
+
+	switch t.Installer {
+	case installerDeb:
+		installed = debInstalledQuery
+		remove = "sudo dpkg -r falcon-sensor"
+	case installerZypper:
+		remove = "sudo zypper -n rm falcon-sensor"
+	}
+
+	return []Resource{
+		{
+			ID: t.ID + "-uninstall",
+			Exec: &ExecResource{
+				Validate: Exec{Script: linuxUninstallValidateScript(installed), Interpreter: InterpreterShell},
+				Enforce: &Exec{
+					Script:      p.linuxMaintenanceTokenScript() + linuxUninstallScript(installed, remove),
+					Interpreter: InterpreterShell,
+				},
+			},
+		},
+	}
+}
+
+// linuxUninstallValidateScript exits 100 when the sensor package is not installed.
+func linuxUninstallValidateScript(installed string) string {
+	return `if ` + installed + ` ; then
+  exit 101
+fi
+exit 100
+`
+}
+
+// linuxUninstallScript removes the sensor package.
+func linuxUninstallScript(installed string, remove string) string {
+	return remove + `
+if ` + installed + ` ; then
+  echo "Unable to remove the Falcon Sensor"
+  exit 101
+fi
+exit 100
+`
+}
+
+const windowsUninstallValidateScript = `$agentService = Get-Service -Name CSAgent -ErrorAction SilentlyContinue
+if ($agentService) {
+    Exit 101
+}
+Exit 100
+`
+
+// windowsUninstallScript runs the cached sensor installer with /uninstall. maintenanceToken is the
+// script that adds the maintenance token to $uninstallArguments.
+func windowsUninstallScript(maintenanceToken string) string {
+	return `$uninstallKeys = @(
+    'HKLM:\SOFTWARE\Microsoft\Windows\CurrentVersion\Uninstall\*',
+    'HKLM:\SOFTWARE\WOW6432Node\Microsoft\Windows\CurrentVersion\Uninstall\*'
+)
+$sensor = Get-ItemProperty -Path $uninstallKeys -ErrorAction SilentlyContinue |
+    Where-Object { $_.DisplayName -eq 'CrowdStrike Windows Sensor' -and $_.BundleCachePath } |
+    Select-Object -First 1
+if (-not $sensor) {
+    Write-Output 'Unable to find the Falcon Sensor uninstaller'
+    Exit 101
+}
+
+$uninstallArguments = @('/uninstall', '/quiet', '/norestart')
+` + maintenanceToken + `$uninstallerProcess = Start-Process -FilePath $sensor.BundleCachePath -ArgumentList $uninstallArguments -PassThru -Wait
+
+if ($uninstallerProcess.ExitCode -ne 0) {
+    Write-Output "Uninstaller returned exit code $($uninstallerProcess.ExitCode)"
+    Exit 101
+}
+Exit 100
+`
+}
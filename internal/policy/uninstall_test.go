@@ -0,0 +1,45 @@
+package policy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUninstall(t *testing.T) {
+	p := NewPolicy("cid", "", "", SensorOptions{}, nil, nil, nil)
+	p.Uninstall = true
+	p.Hooks = map[string]Hooks{
+		PlatformLinux: {PreInstall: &Hook{Validate: "exit 100\nexit 101", Enforce: "exit 100"}},
+	}
+
+	assignment, err := p.OSPolicyAssignment()
+	require.NoError(t, err)
+	require.NoError(t, assignment.Validate())
+	assert.Equal(t, UninstallPolicyID, assignment.OSPolicies[0].ID)
+
+	for _, g := range assignment.OSPolicies[0].ResourceGroups {
+		require.Len(t, g.Resources, 1, g.InventoryFilters[0].OSShortName)
+	}
+
+	tests := []struct {
+		id       string
+		validate string
+		enforce  string
+	}{
+		{"rhel9-uninstall", rpmInstalledQuery, "rpm -e falcon-sensor"},
+		{"suse15-uninstall", rpmInstalledQuery, "zypper -n rm falcon-sensor"},
+		{"ubuntu-uninstall", debInstalledQuery, "dpkg -r falcon-sensor"},
+		{"windows-uninstall", "Get-Service -Name CSAgent", "'/uninstall'"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.id, func(t *testing.T) {
+			r := findResource(t, assignment, tt.id)
+			assert.Contains(t, r.Exec.Validate.Script, tt.validate)
+			assert.Contains(t, r.Exec.Enforce.Script, tt.enforce)
+			assert.NotContains(t, r.Exec.Enforce.Script, "maintenance-token")
+			assert.NotContains(t, r.Exec.Enforce.Script, "MAINTENANCE_TOKEN")
+		})
+	}
+}
@@ -0,0 +1,32 @@
+package policy
+
+const (
+	rpmVersionQuery = "rpm -q --qf '%{VERSION}-%{RELEASE}' falcon-sensor"
+	debVersionQuery = "dpkg-query -W -f='${Version}' falcon-sensor"
+)
+
+// upgradeResources stages the installer and installs it whenever the installed sensor is older
+// than the staged sensor. Package managers upgrade the sensor in place and keep its configuration.
+func (p Policy) upgradeResources(t osTarget) []Resource {
+	query := rpmVersionQuery
+	if t.Installer == installerDeb {
+		query = debVersionQuery
+	}
+
+	validate := linuxVersionScript(query, t.resource(p).Version)
+	enforce := p.linuxMaintenanceTokenScript() + p.installCommand(t) + "\n" + validate
+	if p.verifiesSignature(t) {
+		enforce = p.verifySignatureScript(t) + enforce
+	}
+
+	return append(p.stagingResources(t),
+		Resource{
+			ID: t.ID + "-install",
+			Exec: &ExecResource{
+				Validate: Exec{Script: validate, Interpreter: InterpreterShell},
+				Enforce:  &Exec{Script: p.linuxStageScript(t) + enforce, Interpreter: InterpreterShell},
+			},
+		},
+		p.linuxConfigure(t, t.Installer == installerDeb),
+	)
+}
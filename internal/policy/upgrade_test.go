@@ -0,0 +1,74 @@
+package policy
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnforceVersion(t *testing.T) {
+	p := NewPolicy("cid", "", "", SensorOptions{}, stagedSensors(), nil, nil)
+	p.EnforceVersion = true
+	assignment, err := p.OSPolicyAssignment()
+	require.NoError(t, err)
+	require.NoError(t, assignment.Validate())
+
+	rhel := findResource(t, assignment, "rhel9-install")
+	assert.Contains(t, rhel.Exec.Validate.Script, "staged=7.10.17706")
+	assert.Contains(t, rhel.Exec.Validate.Script, rpmVersionQuery)
+	assert.Contains(t, rhel.Exec.Enforce.Script, "yum -y install /tmp/falcon-sensor.rpm")
+
+	ubuntu := findResource(t, assignment, "ubuntu-install")
+	assert.Contains(t, ubuntu.Exec.Validate.Script, debVersionQuery)
+	assert.Contains(t, ubuntu.Exec.Enforce.Script, "apt-get install -y /tmp/falcon-sensor.deb")
+	assert.Equal(t, "/tmp/falcon-sensor.deb", findResource(t, assignment, "ubuntu-stage-installer").File.Path)
+
+	windows := findResource(t, assignment, "windows-install")
+	assert.Contains(t, windows.Exec.Validate.Script, "[version]'7.10.17706'")
+
+	p.Sles15.Version = ""
+	_, err = p.OSPolicyAssignment()
+	assert.ErrorContains(t, err, "sles15")
+}
+
+// fakeRpm reports the installed package version from $INSTALLED, nothing is installed when unset.
+const fakeRpm = `#!/bin/sh
+[ -n "$INSTALLED" ] || exit 1
+printf '%s' "$INSTALLED"
+`
+
+func TestLinuxVersionScript(t *testing.T) {
+	for _, bin := range []string{"sh", "sed", "sort", "head"} {
+		if _, err := exec.LookPath(bin); err != nil {
+			t.Skipf("%s is required: %s", bin, err)
+		}
+	}
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "rpm"), []byte(fakeRpm), 0o755))
+	script := linuxVersionScript(rpmVersionQuery, "7.10.17706")
+
+	tests := []struct {
+		installed string
+		exitCode  int
+	}{
+		{"7.10.0-17706.el9", 100},
+		{"7.11.0-17801.el9", 100},
+		{"7.9.0-17500.el9", 101},
+		{"7.10.0-17605.el9", 101},
+		{"", 101},
+	}
+	for _, tt := range tests {
+		t.Run(tt.installed, func(t *testing.T) {
+			cmd := exec.Command("sh", "-c", script)
+			cmd.Env = append(os.Environ(), "INSTALLED="+tt.installed, "PATH="+dir+":"+os.Getenv("PATH"))
+			var exitErr *exec.ExitError
+			require.ErrorAs(t, cmd.Run(), &exitErr)
+			assert.Equal(t, tt.exitCode, exitErr.ExitCode())
+		})
+	}
+}
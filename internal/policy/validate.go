@@ -0,0 +1,278 @@
+package policy
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// idPattern matches the ids GCP accepts for os policies and resources.
+var idPattern = regexp.MustCompile(`^[a-z]([a-z0-9-]{0,61}[a-z0-9])?$`)
+
+var durationPattern = regexp.MustCompile(`^[0-9]+s$`)
+
+// absolutePathPattern matches absolute Linux and Windows paths.
+var absolutePathPattern = regexp.MustCompile(`^(/|[A-Za-z]:\\)`)
+
+// validateExitCodes are the exit codes a validate script uses to report that the resource is
+// in (100) or not in (101) the desired state.
+var validateExitCodes = []string{"exit 100", "exit 101"}
+
+// ValidationError describes a problem with the generated os policy assignment.
+type ValidationError struct {
+	// ResourceID is the id of the offending resource, if any.
+	ResourceID string
+	Field      string
+	Message    string
+}
+
+func (e *ValidationError) Error() string {
+	if e.ResourceID != "" {
+		return fmt.Sprintf("resource %s: %s: %s", e.ResourceID, e.Field, e.Message)
+	}
+	return fmt.Sprintf("%s: %s", e.Field, e.Message)
+}
+
+// Validate checks the policy's os policy assignment against the OS Config schema.
+func (p Policy) Validate() error {
+	a, err := p.OSPolicyAssignment()
+	if err != nil {
+		return err
+	}
+	return a.Validate()
+}
+
+// Validate checks the assignment against the OS Config schema so mistakes are caught before
+// gcloud rejects the assignment in every zone. All problems are returned joined together.
+func (a OSPolicyAssignment) Validate() error {
+	v := &validator{ids: map[string]bool{}}
+
+	if len(a.OSPolicies) == 0 {
+		v.fail("", "osPolicies", "at least one os policy is required")
+	}
+
+	for i, p := range a.OSPolicies {
+		v.osPolicy(i, p)
+	}
+
+	v.instanceFilter(a.InstanceFilter)
+	v.rollout(a.Rollout)
+
+	return errors.Join(v.errs...)
+}
+
+type validator struct {
+	errs []error
+	// ids are the resource ids seen so far, resource ids must be unique within the assignment.
+	ids map[string]bool
+}
+
+func (v *validator) fail(resourceID string, field string, format string, args ...any) {
+	v.errs = append(v.errs, &ValidationError{
+		ResourceID: resourceID,
+		Field:      field,
+		Message:    fmt.Sprintf(format, args...),
+	})
+}
+
+func (v *validator) osPolicy(i int, p OSPolicy) {
+	field := fmt.Sprintf("osPolicies[%d]", i)
+
+	if !idPattern.MatchString(p.ID) {
+		v.fail("", field+".id", "invalid id %q", p.ID)
+	}
+
+	if p.Mode != ModeEnforcement && p.Mode != ModeValidation {
+		v.fail("", field+".mode", "must be one of %s, %s got %q", ModeEnforcement, ModeValidation, p.Mode)
+	}
+
+	if len(p.ResourceGroups) == 0 {
+		v.fail("", field+".resourceGroups", "at least one resource group is required")
+	}
+
+	for j, g := range p.ResourceGroups {
+		groupField := fmt.Sprintf("%s.resourceGroups[%d]", field, j)
+
+		for k, f := range g.InventoryFilters {
+			if f.OSShortName == "" {
+				v.fail("", fmt.Sprintf("%s.inventoryFilters[%d].osShortName", groupField, k), "is required")
+			}
+		}
+
+		if len(g.Resources) == 0 {
+			v.fail("", groupField+".resources", "at least one resource is required")
+		}
+
+		for _, r := range g.Resources {
+			v.resource(r)
+		}
+	}
+}
+
+func (v *validator) resource(r Resource) {
+	if !idPattern.MatchString(r.ID) {
+		v.fail(r.ID, "id", "invalid id %q", r.ID)
+	}
+
+	if v.ids[r.ID] {
+		v.fail(r.ID, "id", "duplicate resource id")
+	}
+	v.ids[r.ID] = true
+
+	set := 0
+	for _, ok := range []bool{r.Pkg != nil, r.File != nil, r.Exec != nil} {
+		if ok {
+			set++
+		}
+	}
+	if set != 1 {
+		v.fail(r.ID, "resource", "exactly one of pkg, file or exec is required")
+	}
+
+	if r.Pkg != nil {
+		v.pkg(r.ID, r.Pkg)
+	}
+
+	if r.File != nil {
+		v.file(r.ID, r.File)
+	}
+
+	if r.Exec != nil {
+		v.exec(r.ID, r.Exec)
+	}
+}
+
+func (v *validator) pkg(id string, p *PkgResource) {
+	if p.DesiredState != PackageInstalled && p.DesiredState != "REMOVED" {
+		v.fail(id, "pkg.desiredState", "must be one of INSTALLED, REMOVED got %q", p.DesiredState)
+	}
+
+	switch {
+	case p.Rpm != nil && p.Deb != nil, p.Rpm == nil && p.Deb == nil:
+		v.fail(id, "pkg", "exactly one of rpm or deb is required")
+	case p.Rpm != nil:
+		v.source(id, "pkg.rpm.source", p.Rpm.Source)
+	default:
+		v.source(id, "pkg.deb.source", p.Deb.Source)
+	}
+}
+
+func (v *validator) file(id string, f *FileResource) {
+	if !absolutePathPattern.MatchString(f.Path) {
+		v.fail(id, "file.path", "must be an absolute path got %q", f.Path)
+	}
+
+	if f.Permissions != "" && !modePattern.MatchString(f.Permissions) {
+		v.fail(id, "file.permissions", "must be an octal file mode e.g. 755 got %q", f.Permissions)
+	}
+
+	switch f.State {
+	case "PRESENT", "ABSENT", FileContentsMatch:
+	default:
+		v.fail(id, "file.state", "must be one of PRESENT, ABSENT, CONTENTS_MATCH got %q", f.State)
+	}
+
+	v.source(id, "file.file", f.File)
+}
+
+func (v *validator) source(id string, field string, f File) {
+	if f.Gcs == nil {
+		v.fail(id, field+".gcs", "is required")
+		return
+	}
+
+	if f.Gcs.Bucket == "" {
+		v.fail(id, field+".gcs.bucket", "is required")
+	}
+
+	if f.Gcs.Object == "" {
+		v.fail(id, field+".gcs.object", "is required")
+	}
+
+	if f.Gcs.Generation <= 0 {
+		v.fail(id, field+".gcs.generation", "must be set to the generation of the staged object")
+	}
+}
+
+func (v *validator) exec(id string, e *ExecResource) {
+	v.script(id, "exec.validate", e.Validate, validateExitCodes)
+
+	if e.Enforce != nil {
+		// enforce scripts report success with 100, anything else is a failure.
+		v.script(id, "exec.enforce", *e.Enforce, []string{"exit 100"})
+	}
+}
+
+func (v *validator) script(id string, field string, e Exec, exitCodes []string) {
+	if e.Interpreter != InterpreterShell && e.Interpreter != InterpreterPowerShell {
+		v.fail(
+			id,
+			field+".interpreter",
+			"must be one of %s, %s got %q",
+			InterpreterShell,
+			InterpreterPowerShell,
+			e.Interpreter,
+		)
+	}
+
+	if strings.TrimSpace(e.Script) == "" {
+		v.fail(id, field+".script", "is required")
+		return
+	}
+
+	script := strings.ToLower(e.Script)
+	for _, code := range exitCodes {
+		if !strings.Contains(script, code) {
+			v.fail(id, field+".script", "must %s", code)
+		}
+	}
+}
+
+func (v *validator) instanceFilter(f InstanceFilter) {
+	hasLabels := len(f.InclusionLabels) > 0 || len(f.ExclusionLabels) > 0
+
+	if f.All && hasLabels {
+		v.fail("", "instanceFilter.all", "cannot be combined with label filters")
+	}
+
+	if !f.All && !hasLabels {
+		v.fail("", "instanceFilter", "must target all VMs or specify label filters")
+	}
+
+	for _, filter := range []struct {
+		name string
+		sets []LabelSet
+	}{
+		{name: "inclusionLabels", sets: f.InclusionLabels},
+		{name: "exclusionLabels", sets: f.ExclusionLabels},
+	} {
+		name := filter.name
+		for i, s := range filter.sets {
+			if len(s.Labels) == 0 {
+				v.fail("", fmt.Sprintf("instanceFilter.%s[%d]", name, i), "at least one label is required")
+			}
+
+			for k := range s.Labels {
+				if k == "" {
+					v.fail("", fmt.Sprintf("instanceFilter.%s[%d]", name, i), "label names cannot be empty")
+				}
+			}
+		}
+	}
+}
+
+func (v *validator) rollout(r Rollout) {
+	b := r.DisruptionBudget
+
+	switch {
+	case b.Fixed > 0 && b.Percent > 0:
+		v.fail("", "rollout.disruptionBudget", "only one of fixed or percent may be set")
+	case b.Fixed < 0, b.Percent < 0, b.Percent > 100, b.Fixed == 0 && b.Percent == 0:
+		v.fail("", "rollout.disruptionBudget", "must be a positive number of VMs or a percentage from 1 to 100")
+	}
+
+	if !durationPattern.MatchString(r.MinWaitDuration) {
+		v.fail("", "rollout.minWaitDuration", "must be a number of seconds e.g. 300s got %q", r.MinWaitDuration)
+	}
+}
@@ -0,0 +1,114 @@
+package policy
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(t *testing.T, a *OSPolicyAssignment)
+		// want are the resource id and field of the expected errors, empty when the assignment is valid.
+		want []ValidationError
+	}{
+		{
+			name:   "valid",
+			modify: func(_ *testing.T, _ *OSPolicyAssignment) {},
+		},
+		{
+			name: "missing generation",
+			modify: func(t *testing.T, a *OSPolicyAssignment) {
+				findResource(t, *a, "rhel7-install").Pkg.Rpm.Source.Gcs.Generation = 0
+			},
+			want: []ValidationError{{ResourceID: "rhel7-install", Field: "pkg.rpm.source.gcs.generation"}},
+		},
+		{
+			name: "duplicate resource id",
+			modify: func(t *testing.T, a *OSPolicyAssignment) {
+				findResource(t, *a, "rhel8-configure").ID = "rhel7-configure"
+			},
+			want: []ValidationError{{ResourceID: "rhel7-configure", Field: "id"}},
+		},
+		{
+			name: "invalid interpreter",
+			modify: func(t *testing.T, a *OSPolicyAssignment) {
+				findResource(t, *a, "windows-install").Exec.Validate.Interpreter = "CMD"
+			},
+			want: []ValidationError{{ResourceID: "windows-install", Field: "exec.validate.interpreter"}},
+		},
+		{
+			name: "validate script missing exit codes",
+			modify: func(t *testing.T, a *OSPolicyAssignment) {
+				findResource(t, *a, "debian-configure").Exec.Validate.Script = "systemctl is-active falcon-sensor"
+			},
+			want: []ValidationError{
+				{ResourceID: "debian-configure", Field: "exec.validate.script"},
+				{ResourceID: "debian-configure", Field: "exec.validate.script"},
+			},
+		},
+		{
+			name: "relative staged path",
+			modify: func(t *testing.T, a *OSPolicyAssignment) {
+				stage := findResource(t, *a, "suse12-stage-installer")
+				stage.File.Path = "falcon-sensor.rpm"
+				stage.File.Permissions = "rwxr-xr-x"
+			},
+			want: []ValidationError{
+				{ResourceID: "suse12-stage-installer", Field: "file.path"},
+				{ResourceID: "suse12-stage-installer", Field: "file.permissions"},
+			},
+		},
+		{
+			name: "all combined with labels",
+			modify: func(t *testing.T, a *OSPolicyAssignment) {
+				a.InstanceFilter.InclusionLabels = []LabelSet{{Labels: map[string]string{"env": "prod"}}}
+			},
+			want: []ValidationError{{Field: "instanceFilter.all"}},
+		},
+		{
+			name: "no instances targeted",
+			modify: func(t *testing.T, a *OSPolicyAssignment) {
+				a.InstanceFilter.All = false
+			},
+			want: []ValidationError{{Field: "instanceFilter"}},
+		},
+		{
+			name: "invalid rollout",
+			modify: func(t *testing.T, a *OSPolicyAssignment) {
+				a.Rollout = Rollout{DisruptionBudget: DisruptionBudget{Percent: 150}, MinWaitDuration: "5m"}
+			},
+			want: []ValidationError{
+				{Field: "rollout.disruptionBudget"},
+				{Field: "rollout.minWaitDuration"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, err := NewPolicy("cid", "", "", SensorOptions{}, stagedSensors(), nil, nil).OSPolicyAssignment()
+			require.NoError(t, err)
+			tt.modify(t, &a)
+
+			err = a.Validate()
+			if len(tt.want) == 0 {
+				assert.NoError(t, err)
+				return
+			}
+
+			require.Error(t, err)
+
+			var got []ValidationError
+			for _, e := range err.(interface{ Unwrap() []error }).Unwrap() {
+				var verr *ValidationError
+				require.True(t, errors.As(e, &verr))
+				got = append(got, ValidationError{ResourceID: verr.ResourceID, Field: verr.Field})
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,174 @@
+package policy
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+type WaveStatus int
+
+const (
+	WavePending WaveStatus = iota
+	WaveRollingOut
+	WaveVerifying
+	WaveSucceeded
+	WaveFailed
+)
+
+// Wave is a group of assignments that are rolled out together before the next wave starts.
+type Wave struct {
+	Number      int
+	Canary      bool
+	Assignments []*Assignment
+
+	lock         sync.RWMutex
+	status       WaveStatus
+	compliant    int
+	nonCompliant int
+	total        int
+	message      string
+}
+
+// Status returns the current status of the wave.
+func (w *Wave) Status() WaveStatus {
+	w.lock.RLock()
+	defer w.lock.RUnlock()
+	return w.status
+}
+
+// SetStatus updates the status of the wave.
+func (w *Wave) SetStatus(status WaveStatus) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	w.status = status
+}
+
+// Fail marks the wave as failed with the given reason.
+func (w *Wave) Fail(message string) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	w.status = WaveFailed
+	w.message = message
+}
+
+// Message returns the reason the wave failed, if any.
+func (w *Wave) Message() string {
+	w.lock.RLock()
+	defer w.lock.RUnlock()
+	return w.message
+}
+
+// SetCompliance records the latest compliance counts of the VMs in the wave.
+func (w *Wave) SetCompliance(compliant int, nonCompliant int, total int) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	w.compliant = compliant
+	w.nonCompliant = nonCompliant
+	w.total = total
+}
+
+// Compliance returns the latest compliance counts of the VMs in the wave.
+func (w *Wave) Compliance() (compliant int, nonCompliant int, total int) {
+	w.lock.RLock()
+	defer w.lock.RUnlock()
+	return w.compliant, w.nonCompliant, w.total
+}
+
+// PlanWaves splits the assignments into waves.
+//
+// Assignments in canaryZones make up the first wave and the remaining assignments are split
+// as evenly as possible, in order, into the remaining waves. Without canary zones the first
+// wave is the first split of the assignments.
+func PlanWaves(assignments []*Assignment, count int, canaryZones []string) ([]*Wave, error) {
+	if count < 1 {
+		return nil, fmt.Errorf("invalid number of rollout waves %d: must be at least 1", count)
+	}
+
+	canary := map[string]bool{}
+	for _, z := range canaryZones {
+		canary[z] = true
+	}
+
+	var canaries []*Assignment
+	var rest []*Assignment
+	found := map[string]bool{}
+	for _, a := range assignments {
+		if canary[a.Zone] {
+			canaries = append(canaries, a)
+			found[a.Zone] = true
+			continue
+		}
+		rest = append(rest, a)
+	}
+
+	var missing []string
+	for _, z := range canaryZones {
+		if !found[z] {
+			missing = append(missing, z)
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return nil, fmt.Errorf("canary zones are not targeted: %s", strings.Join(missing, ", "))
+	}
+
+	var waves []*Wave
+	if len(canaries) > 0 {
+		waves = append(waves, &Wave{Canary: true, Assignments: canaries})
+		count--
+	}
+
+	if count < 1 && len(rest) > 0 {
+		count = 1
+	}
+
+	if count > len(rest) {
+		count = len(rest)
+	}
+
+	for i := 0; i < count; i++ {
+		start := i * len(rest) / count
+		end := (i + 1) * len(rest) / count
+		waves = append(waves, &Wave{
+			Canary:      len(canaries) == 0 && i == 0,
+			Assignments: rest[start:end],
+		})
+	}
+
+	for i, w := range waves {
+		w.Number = i + 1
+	}
+
+	return waves, nil
+}
+
+// EvaluateWave decides whether a wave may proceed based on the compliance of its VMs.
+//
+// total is the number of VMs the wave's assignments cover, so VMs whose compliance report is
+// unknown or has not been sent yet count as not compliant. threshold and failureLimit are
+// percentages of total. done is true once the compliant percentage reaches threshold. An error is
+// returned once the non-compliant percentage exceeds failureLimit.
+//
+// Compliance reports lag the rollout, so a wave without any reports is not done.
+func EvaluateWave(compliant int, nonCompliant int, total int, threshold float64, failureLimit float64) (bool, error) {
+	// VMs created after the wave was counted may report too.
+	total = max(total, compliant+nonCompliant)
+	if total == 0 {
+		return false, nil
+	}
+
+	failed := float64(nonCompliant) / float64(total) * 100
+	if failed > failureLimit {
+		return false, fmt.Errorf(
+			"%d of %d VMs (%.0f%%) are non-compliant, exceeding the failure limit of %.0f%%",
+			nonCompliant,
+			total,
+			failed,
+			failureLimit,
+		)
+	}
+
+	return float64(compliant)/float64(total)*100 >= threshold, nil
+}
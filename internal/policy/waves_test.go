@@ -0,0 +1,117 @@
+package policy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func zonesOf(w *Wave) []string {
+	var zones []string
+	for _, a := range w.Assignments {
+		zones = append(zones, a.Zone)
+	}
+	return zones
+}
+
+func TestPlanWaves(t *testing.T) {
+	var assignments []*Assignment
+	for _, z := range []string{"a", "b", "c", "d", "e"} {
+		assignments = append(assignments, &Assignment{Zone: z})
+	}
+
+	tests := []struct {
+		name    string
+		count   int
+		canary  []string
+		want    [][]string
+		wantErr bool
+	}{
+		{
+			name:  "single wave",
+			count: 1,
+			want:  [][]string{{"a", "b", "c", "d", "e"}},
+		},
+		{
+			name:  "even split",
+			count: 2,
+			want:  [][]string{{"a", "b"}, {"c", "d", "e"}},
+		},
+		{
+			name:   "canary first",
+			count:  3,
+			canary: []string{"d"},
+			want:   [][]string{{"d"}, {"a", "b"}, {"c", "e"}},
+		},
+		{
+			name:   "canary with a single wave still rolls out the rest",
+			count:  1,
+			canary: []string{"a"},
+			want:   [][]string{{"a"}, {"b", "c", "d", "e"}},
+		},
+		{
+			name:  "more waves than zones",
+			count: 10,
+			want:  [][]string{{"a"}, {"b"}, {"c"}, {"d"}, {"e"}},
+		},
+		{
+			name:    "unknown canary",
+			count:   2,
+			canary:  []string{"z"},
+			wantErr: true,
+		},
+		{
+			name:    "no waves",
+			count:   0,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			waves, err := PlanWaves(assignments, tt.count, tt.canary)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+
+			var got [][]string
+			for i, w := range waves {
+				assert.Equal(t, i+1, w.Number)
+				got = append(got, zonesOf(w))
+			}
+			assert.Equal(t, tt.want, got)
+			assert.True(t, waves[0].Canary)
+		})
+	}
+}
+
+func TestEvaluateWave(t *testing.T) {
+	done, err := EvaluateWave(0, 0, 0, 90, 10)
+	assert.NoError(t, err)
+	assert.False(t, done, "no reports yet")
+
+	done, err = EvaluateWave(9, 0, 11, 90, 10)
+	assert.NoError(t, err)
+	assert.False(t, done, "unknown VMs count against the threshold")
+
+	done, err = EvaluateWave(1, 0, 10, 90, 10)
+	assert.NoError(t, err)
+	assert.False(t, done, "VMs without a report yet count against the threshold")
+
+	done, err = EvaluateWave(9, 1, 8, 90, 10)
+	assert.NoError(t, err)
+	assert.True(t, done, "VMs created after the wave was counted are included")
+
+	done, err = EvaluateWave(8, 0, 10, 90, 10)
+	assert.NoError(t, err)
+	assert.False(t, done)
+
+	done, err = EvaluateWave(9, 1, 10, 90, 10)
+	assert.NoError(t, err)
+	assert.True(t, done)
+
+	_, err = EvaluateWave(7, 3, 10, 90, 10)
+	assert.EqualError(t, err, "3 of 10 VMs (30%) are non-compliant, exceeding the failure limit of 10%")
+}
@@ -56,3 +56,10 @@ func (pw *ProgressWriter) Done() bool {
 	defer pw.lock.RUnlock()
 	return pw.total == pw.n
 }
+
+// Set sets the number of bytes written, for transfers that report a running total.
+func (pw *ProgressWriter) Set(n int64) {
+	pw.lock.Lock()
+	defer pw.lock.Unlock()
+	pw.n = n
+}
@@ -0,0 +1,93 @@
+// Package redact scrubs secrets and identifying values from text before it leaves the machine.
+package redact
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Placeholder replaces any redacted value.
+const Placeholder = "[REDACTED]"
+
+// minValueLength avoids replacing very short user values that would mangle unrelated text.
+const minValueLength = 4
+
+type pattern struct {
+	re   *regexp.Regexp
+	repl string
+}
+
+// patterns match well known secret and identifier shapes.
+var patterns = []pattern{
+	{
+		re:   regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----`),
+		repl: Placeholder,
+	},
+	{re: regexp.MustCompile(`(?i)\b(bearer|basic)\s+[A-Za-z0-9\-._~+/]+=*`), repl: "$1 " + Placeholder},
+	{re: regexp.MustCompile(`\beyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`), repl: Placeholder},
+	{re: regexp.MustCompile(`\bya29\.[0-9A-Za-z\-_.]+`), repl: Placeholder},
+	{re: regexp.MustCompile(`\bAIza[0-9A-Za-z\-_]{35}\b`), repl: Placeholder},
+	{
+		re:   regexp.MustCompile(`(?i)\b(client_secret|access_token|refresh_token|password|secret|token|api_key)(["']?\s*[:=]\s*["']?)[^\s"'&,]+`),
+		repl: "${1}${2}" + Placeholder,
+	},
+	// falcon cids, client ids and other 32 character hex identifiers
+	{re: regexp.MustCompile(`\b[0-9a-fA-F]{32}(-[0-9a-fA-F]{2})?\b`), repl: Placeholder},
+	{re: regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`), repl: Placeholder},
+	{re: regexp.MustCompile(`\bgs://[^/\s"']+`), repl: "gs://" + Placeholder},
+	{re: regexp.MustCompile(`\bprojects/[^/\s"']+`), repl: "projects/" + Placeholder},
+}
+
+// Redactor replaces secret shapes and registered values in text.
+type Redactor struct {
+	lock   sync.RWMutex
+	values map[string]struct{}
+}
+
+// New creates a Redactor that also scrubs the given values.
+func New(values ...string) *Redactor {
+	r := &Redactor{values: map[string]struct{}{}}
+	r.Add(values...)
+	return r
+}
+
+// Add registers user supplied values that must be scrubbed.
+func (r *Redactor) Add(values ...string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	for _, v := range values {
+		v = strings.TrimSpace(v)
+		if len(v) < minValueLength {
+			continue
+		}
+		r.values[v] = struct{}{}
+	}
+}
+
+// String returns s with all registered values and known secret shapes replaced.
+func (r *Redactor) String(s string) string {
+	r.lock.RLock()
+	values := make([]string, 0, len(r.values))
+	for v := range r.values {
+		values = append(values, v)
+	}
+	r.lock.RUnlock()
+
+	// replace longest values first so a value containing another is fully removed
+	sort.Slice(values, func(i, j int) bool {
+		return len(values[i]) > len(values[j])
+	})
+
+	for _, v := range values {
+		s = strings.ReplaceAll(s, v, Placeholder)
+	}
+
+	for _, p := range patterns {
+		s = p.re.ReplaceAllString(s, p.repl)
+	}
+
+	return s
+}
@@ -0,0 +1,65 @@
+package redact
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedactor_String(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []string
+		input  string
+		want   string
+	}{
+		{
+			name:  "bearer token",
+			input: "request failed: Authorization: Bearer abc.def-123",
+			want:  "request failed: Authorization: Bearer [REDACTED]",
+		},
+		{
+			name:  "falcon cid with checksum",
+			input: "using cid 0123456789ABCDEF0123456789ABCDEF-A1 for install",
+			want:  "using cid [REDACTED] for install",
+		},
+		{
+			name:  "google oauth token",
+			input: "token ya29.a0AfH6SMB-abc_123 expired",
+			want:  "token [REDACTED] expired",
+		},
+		{
+			name:  "key value secret",
+			input: "client_secret=hunter2hunter2&grant_type=client_credentials",
+			want:  "client_secret=[REDACTED]&grant_type=client_credentials",
+		},
+		{
+			name:  "service account email",
+			input: "permission denied for sa@my-project.iam.gserviceaccount.com",
+			want:  "permission denied for [REDACTED]",
+		},
+		{
+			name:  "gcloud resource paths",
+			input: "ERROR: (gcloud) projects/my-project/locations/us-central1-a not found in gs://my-bucket/crowdstrike",
+			want:  "ERROR: (gcloud) projects/[REDACTED]/locations/us-central1-a not found in gs://[REDACTED]/crowdstrike",
+		},
+		{
+			name:   "user supplied values",
+			values: []string{"my-sensor-bucket", "acme-prod-project", "ab"},
+			input:  "bucket my-sensor-bucket in acme-prod-project: ab",
+			want:   "bucket [REDACTED] in [REDACTED]: ab",
+		},
+		{
+			name:  "unrelated text is untouched",
+			input: "ALREADY_EXISTS: Requested entity already exists",
+			want:  "ALREADY_EXISTS: Requested entity already exists",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := New(tt.values...)
+			assert.Equal(t, tt.want, r.String(tt.input))
+		})
+	}
+}
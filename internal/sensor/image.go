@@ -0,0 +1,230 @@
+package sensor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"sync"
+
+	retry "github.com/avast/retry-go/v4"
+	"github.com/crowdstrike/gcp-os-policy/internal/falconutil"
+	"github.com/crowdstrike/gcp-os-policy/internal/logging"
+	"github.com/crowdstrike/gcp-os-policy/internal/progress"
+	"github.com/crowdstrike/gofalcon/falcon"
+	"github.com/crowdstrike/gofalcon/falcon/client"
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/google"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+)
+
+// imageTagPattern matches node sensor tags, e.g. 7.20.0-17306-1.falcon-linux.Release.US-1, and
+// captures the version, build and revision.
+var imageTagPattern = regexp.MustCompile(`^(\d+)\.(\d+)\.(\d+)-(\d+)-(\d+)\.`)
+
+// Image is the Falcon node sensor image staged into an Artifact Registry repository.
+type Image struct {
+	Cloud falcon.CloudType
+	// Repository is the Artifact Registry repository the image is pushed to, e.g.
+	// us-docker.pkg.dev/my-project/falcon.
+	Repository     string
+	Tag            string
+	Digest         string
+	ProgressWriter *progress.ProgressWriter
+	Logger         *slog.Logger
+}
+
+// Reference returns the staged image's reference pinned to its digest, e.g.
+// us-docker.pkg.dev/my-project/falcon/falcon-sensor:7.20.0-17306-1.falcon-linux.Release.US-1@sha256:...
+func (i *Image) Reference() string {
+	return fmt.Sprintf("%s/%s:%s@%s", i.Repository, falcon.NodeSensor, i.Tag, i.Digest)
+}
+
+// StageToArtifactRegistry copies the n-1 node sensor image from the CrowdStrike registry into the
+// Artifact Registry repository, the same version StreamToBucket selects for installers. An image
+// that is already staged is reused.
+func (i *Image) StageToArtifactRegistry(
+	ctx context.Context,
+	client *client.CrowdStrikeAPISpecification,
+	cid string,
+) error {
+	logger := logging.Or(i.Logger).With("image", falcon.NodeSensor)
+	i.ProgressWriter = progress.NewProgressWriter()
+
+	username, token, err := falconutil.RegistryCredentials(client, cid)
+	if err != nil {
+		return err
+	}
+
+	source, err := name.NewRepository(falcon.FalconContainerSensorImageURI(i.Cloud, falcon.NodeSensor))
+	if err != nil {
+		return err
+	}
+
+	sourceOptions := []remote.Option{
+		remote.WithContext(ctx),
+		remote.WithAuth(&authn.Basic{Username: username, Password: token}),
+		remote.WithTransport(logging.NewTransport(http.DefaultTransport, logger, "registry")),
+	}
+
+	logger.Debug("listing node sensor tags", "repository", source.String())
+	tags, err := remote.List(source, sourceOptions...)
+	if err != nil {
+		return fmt.Errorf("failed to list the node sensor images in %s: %w", source, err)
+	}
+
+	i.Tag, err = selectImageTag(tags)
+	if err != nil {
+		return err
+	}
+
+	desc, err := remote.Get(source.Tag(i.Tag), sourceOptions...)
+	if err != nil {
+		return fmt.Errorf("failed to read the node sensor image %s: %w", source.Tag(i.Tag), err)
+	}
+	i.Digest = desc.Digest.String()
+
+	destination, err := name.NewTag(fmt.Sprintf("%s/%s:%s", i.Repository, falcon.NodeSensor, i.Tag))
+	if err != nil {
+		return fmt.Errorf("invalid Artifact Registry repository %q: %w", i.Repository, err)
+	}
+
+	destinationOptions := []remote.Option{
+		remote.WithContext(ctx),
+		remote.WithAuthFromKeychain(google.Keychain),
+		remote.WithTransport(logging.NewTransport(http.DefaultTransport, logger, "artifactregistry")),
+	}
+
+	existing, err := remote.Head(destination, destinationOptions...)
+	if err == nil && existing.Digest == desc.Digest {
+		logger.Info("node sensor image already exists in repository", "image", destination.String())
+		i.ProgressWriter.SetTotal(existing.Size)
+		i.ProgressWriter.Set(existing.Size)
+		return nil
+	}
+
+	var terr *transport.Error
+	if err != nil && !(errors.As(err, &terr) && terr.StatusCode == http.StatusNotFound) {
+		return fmt.Errorf("failed to check if the node sensor image exists in %s: %w", i.Repository, err)
+	}
+
+	var attemptNum uint
+	err = retry.Do(
+		func() error {
+			logger.Debug("copying node sensor image", "source", source.Tag(i.Tag).String(), "attempt", attemptNum+1)
+			return i.copyImage(desc, destination, destinationOptions)
+		},
+		retry.Attempts(maxRetries+1),
+		retry.Delay(initialBackoff),
+		retry.MaxDelay(maxBackoff),
+		retry.DelayType(retry.BackOffDelay),
+		retry.OnRetry(func(n uint, err error) {
+			attemptNum = n + 1
+			logger.Warn("retrying node sensor image copy", "attempt", attemptNum+1, "error", err)
+		}),
+		retry.LastErrorOnly(true),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to push the node sensor image to %s: %w", i.Repository, err)
+	}
+
+	logger.Info("node sensor image pushed to repository", "image", destination.String(), "digest", i.Digest)
+	return nil
+}
+
+// copyImage writes the image or multi-platform index to the destination, reporting the uploaded
+// layers to the ProgressWriter. Layers that already exist in the repository are not uploaded again.
+func (i *Image) copyImage(desc *remote.Descriptor, destination name.Tag, options []remote.Option) error {
+	var index v1.ImageIndex
+	var img v1.Image
+	var err error
+	if desc.MediaType.IsIndex() {
+		index, err = desc.ImageIndex()
+	} else {
+		img, err = desc.Image()
+	}
+	if err != nil {
+		return err
+	}
+
+	// the write closes the updates channel when it returns.
+	updates := make(chan v1.Update, 16)
+	options = append(slices.Clone(options), remote.WithProgress(updates))
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for u := range updates {
+			if u.Error != nil {
+				continue
+			}
+			i.ProgressWriter.SetTotal(u.Total)
+			i.ProgressWriter.Set(u.Complete)
+		}
+	}()
+
+	if index != nil {
+		err = remote.WriteIndex(destination, index, options...)
+	} else {
+		err = remote.Write(destination, img, options...)
+	}
+	wg.Wait()
+
+	if err == nil {
+		// mark the transfer complete when every layer already existed in the repository.
+		i.ProgressWriter.SetTotal(max(i.ProgressWriter.Total(), 1))
+		i.ProgressWriter.Set(i.ProgressWriter.Total())
+	}
+
+	return err
+}
+
+// selectImageTag returns the n-1 node sensor tag: the highest revision of the second newest
+// version and build. Revisions of the same build are repackages and do not count as a version.
+func selectImageTag(tags []string) (string, error) {
+	type versionedTag struct {
+		tag     string
+		version []int
+	}
+
+	var versioned []versionedTag
+	for _, tag := range tags {
+		m := imageTagPattern.FindStringSubmatch(tag)
+		if m == nil {
+			continue
+		}
+
+		version := make([]int, 0, len(m)-1)
+		for _, part := range m[1:] {
+			n, _ := strconv.Atoi(part)
+			version = append(version, n)
+		}
+		versioned = append(versioned, versionedTag{tag: tag, version: version})
+	}
+
+	slices.SortFunc(versioned, func(a, b versionedTag) int {
+		return slices.Compare(b.version, a.version)
+	})
+
+	// the tags are sorted newest first, so the first tag of each build is its highest revision.
+	var builds []versionedTag
+	for _, v := range versioned {
+		if len(builds) == 0 || !slices.Equal(builds[len(builds)-1].version[:4], v.version[:4]) {
+			builds = append(builds, v)
+		}
+	}
+
+	if len(builds) < 2 {
+		return "", fmt.Errorf("no n-1 node sensor image found in %d tags", len(tags))
+	}
+
+	return builds[1].tag, nil
+}
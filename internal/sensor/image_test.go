@@ -0,0 +1,63 @@
+package sensor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSelectImageTag(t *testing.T) {
+	tests := []struct {
+		name     string
+		tags     []string
+		expected string
+		wantErr  bool
+	}{
+		{
+			name: "n-1 by version build and revision",
+			tags: []string{
+				"7.19.0-17219-1.falcon-linux.Release.US-1",
+				"7.20.0-17306-1.falcon-linux.Release.US-1",
+				"7.20.0-17306-2.falcon-linux.Release.US-1",
+				"7.9.0-16701-1.falcon-linux.Release.US-1",
+				"latest",
+			},
+			expected: "7.19.0-17219-1.falcon-linux.Release.US-1",
+		},
+		{
+			name: "highest revision of the n-1 build",
+			tags: []string{
+				"7.19.0-17219-1.falcon-linux.Release.US-1",
+				"7.19.0-17219-3.falcon-linux.Release.US-1",
+				"7.19.0-17219-2.falcon-linux.Release.US-1",
+				"7.20.0-17306-1.falcon-linux.Release.US-1",
+			},
+			expected: "7.19.0-17219-3.falcon-linux.Release.US-1",
+		},
+		{
+			name: "revisions of a single build",
+			tags: []string{
+				"7.20.0-17306-1.falcon-linux.Release.US-1",
+				"7.20.0-17306-2.falcon-linux.Release.US-1",
+			},
+			wantErr: true,
+		},
+		{
+			name:    "single version",
+			tags:    []string{"7.20.0-17306-1.falcon-linux.Release.US-1", "latest"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tag, err := selectImageTag(tt.tags)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, tag)
+		})
+	}
+}
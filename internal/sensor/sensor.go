@@ -2,16 +2,22 @@ package sensor
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
+	"log/slog"
+	"os"
 	"path/filepath"
 	"strings"
 	"time"
 
 	"cloud.google.com/go/storage"
 	retry "github.com/avast/retry-go/v4"
+	"github.com/crowdstrike/gcp-os-policy/internal/logging"
 	"github.com/crowdstrike/gcp-os-policy/internal/progress"
+	"github.com/crowdstrike/gcp-os-policy/internal/throttle"
 	"github.com/crowdstrike/gcp-os-policy/internal/versionutil"
 	"github.com/crowdstrike/gofalcon/falcon"
 	"github.com/crowdstrike/gofalcon/falcon/client"
@@ -36,9 +42,21 @@ type Sensor struct {
 	ProgressWriter *progress.ProgressWriter
 	SensorInfo     models.DomainSensorInstallerV1
 	Generation     int64
+	// CacheDir is where the installer is downloaded before it is uploaded, so a failed upload
+	// does not download it again. Defaults to the os temp directory.
+	CacheDir string
+	// ChunkSize is the size of each resumable upload request. A failed chunk is retried without
+	// restarting the upload. Defaults to the storage client's 16 MiB.
+	ChunkSize int
+	// Limiter caps the bandwidth of the download and upload, it is shared by every sensor.
+	Limiter *throttle.Limiter
+	Logger  *slog.Logger
 }
 
-// StreamToBucket will download and upload to a gcp storage bucket at the same time.
+// StreamToBucket will download the sensor to the local cache and upload it to a gcp storage bucket.
+//
+// The progress covers both the download and the upload. An installer left in the cache by an
+// earlier run is reused when its sha256 matches, and it is removed once the upload completes.
 func (s *Sensor) StreamToBucket(
 	ctx context.Context,
 	client *client.CrowdStrikeAPISpecification,
@@ -55,6 +73,9 @@ func (s *Sensor) StreamToBucket(
 	sort = "version"
 	offset = 1
 
+	logger := logging.Or(s.Logger).With("os", s.OsShortName+s.OsVersion)
+	logger.Debug("querying sensor installers", "filter", s.Filter)
+
 	query, err := client.SensorDownload.GetCombinedSensorInstallersByQuery(
 		&sensor_download.GetCombinedSensorInstallersByQueryParams{
 			Filter:  &s.Filter,
@@ -79,60 +100,60 @@ func (s *Sensor) StreamToBucket(
 	s.SensorInfo = *sensorResource
 
 	bucketPath := s.determineBucketPath(sensorResource)
+	logger.Debug(
+		"selected sensor installer",
+		"version", *sensorResource.Version,
+		"name", *sensorResource.Name,
+		"sha256", *sensorResource.Sha256,
+	)
 
 	o := storageClient.Bucket(bucket).
 		Object(filepath.Join(bucketPath, *sensorResource.Version, *sensorResource.Name))
 
+	size := int64(*sensorResource.FileSize)
+	var cachePath string
+
 	var attemptNum uint
 	err = retry.Do(
 		func() error {
-			var attrs *storage.ObjectAttrs
-
-			if attemptNum == 0 {
-				// check if a sensor already exists in the bucket (only on first attempt)
-				attrs, err = o.Attrs(ctx)
-				if err == nil {
-					s.FullPath = fmt.Sprintf("%s/%s", attrs.Bucket, attrs.Name)
-					s.Generation = attrs.Generation
-					return nil
-				}
-				if !errors.Is(err, storage.ErrObjectNotExist) {
-					return fmt.Errorf("failed to check if sensor %s/%s exists in bucket %s: %w",
-						s.OsShortName, s.OsVersion, bucket, err)
-				}
-			} else {
-				s.cleanupPartialUpload(ctx, o)
+			// check if a sensor already exists in the bucket, a retried upload may have completed
+			// without its response being received.
+			attrs, err := o.Attrs(ctx)
+			if err == nil {
+				logger.Info("sensor already exists in bucket", "object", attrs.Name)
+				s.FullPath = fmt.Sprintf("%s/%s", attrs.Bucket, attrs.Name)
+				s.Generation = attrs.Generation
+				s.ProgressWriter.SetTotal(size)
+				s.ProgressWriter.Set(size)
+				return nil
 			}
-
-			s.ProgressWriter.SetTotal(int64(*sensorResource.FileSize))
-			wc := o.NewWriter(ctx)
-
-			_, err = client.SensorDownload.DownloadSensorInstallerByID(
-				&sensor_download.DownloadSensorInstallerByIDParams{
-					ID:      *sensorResource.Sha256,
-					Context: ctx,
-				},
-				io.MultiWriter(wc, s.ProgressWriter),
-			)
-
-			if err != nil {
-				return fmt.Errorf("failed to download sensor %s/%s from CrowdStrike API (size: %d bytes): %w",
-					s.OsShortName, s.OsVersion, *sensorResource.FileSize, err)
+			if !errors.Is(err, storage.ErrObjectNotExist) {
+				return fmt.Errorf("failed to check if sensor %s/%s exists in bucket %s: %w",
+					s.OsShortName, s.OsVersion, bucket, err)
 			}
 
-			if err := wc.Close(); err != nil {
-				return fmt.Errorf("failed to complete upload of sensor %s/%s to bucket %s: %w",
-					s.OsShortName, s.OsVersion, bucket, err)
+			s.ProgressWriter.SetTotal(2 * size)
+
+			if cachePath == "" {
+				s.ProgressWriter.Set(0)
+				logger.Debug("downloading sensor to cache", "attempt", attemptNum+1)
+				cachePath, err = s.download(ctx, client, sensorResource)
+				if err != nil {
+					return err
+				}
 			}
 
-			attrs, err = o.Attrs(ctx)
+			s.ProgressWriter.Set(size)
+			logger.Debug("uploading sensor to bucket", "bucket", bucket, "attempt", attemptNum+1)
+			attrs, err = s.upload(ctx, o, cachePath)
 			if err != nil {
-				return fmt.Errorf("failed to verify upload of sensor %s/%s to bucket %s: %w",
+				return fmt.Errorf("failed to complete upload of sensor %s/%s to bucket %s: %w",
 					s.OsShortName, s.OsVersion, bucket, err)
 			}
 
 			s.FullPath = fmt.Sprintf("%s/%s", attrs.Bucket, attrs.Name)
 			s.Generation = attrs.Generation
+			logger.Info("sensor uploaded to bucket", "object", attrs.Name, "generation", attrs.Generation)
 
 			return nil
 		},
@@ -142,22 +163,131 @@ func (s *Sensor) StreamToBucket(
 		retry.DelayType(retry.BackOffDelay),
 		retry.OnRetry(func(n uint, err error) {
 			attemptNum = n + 1
+			logger.Warn("retrying sensor upload", "attempt", attemptNum+1, "error", err)
 		}),
 		retry.LastErrorOnly(true),
+		retry.Context(ctx),
 	)
+	if err != nil {
+		return err
+	}
 
-	return err
-}
-
-func (s *Sensor) cleanupPartialUpload(ctx context.Context, obj *storage.ObjectHandle) error {
-	if err := obj.Delete(ctx); err != nil {
-		if !errors.Is(err, storage.ErrObjectNotExist) {
-			return fmt.Errorf("failed to cleanup partial upload: %w", err)
+	if cachePath != "" {
+		if err := os.Remove(cachePath); err != nil {
+			logger.Warn("unable to remove cached sensor", "path", cachePath, "error", err)
 		}
 	}
+
 	return nil
 }
 
+// download downloads the sensor installer into the cache directory and returns its path. An
+// installer already in the cache is reused when its sha256 matches.
+func (s *Sensor) download(
+	ctx context.Context,
+	client *client.CrowdStrikeAPISpecification,
+	sensorResource *models.DomainSensorInstallerV1,
+) (string, error) {
+	logger := logging.Or(s.Logger).With("os", s.OsShortName+s.OsVersion)
+
+	dir := s.CacheDir
+	if dir == "" {
+		dir = os.TempDir()
+	}
+
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", fmt.Errorf("failed to create sensor cache directory %s: %w", dir, err)
+	}
+
+	path := filepath.Join(dir, *sensorResource.Sha256)
+	if sum, err := fileSha256(path); err == nil && sum == *sensorResource.Sha256 {
+		logger.Info("using cached sensor", "path", path)
+		return path, nil
+	}
+
+	partial := path + ".part"
+	f, err := os.Create(partial)
+	if err != nil {
+		return "", fmt.Errorf("failed to create sensor cache file %s: %w", partial, err)
+	}
+	defer os.Remove(partial)
+
+	h := sha256.New()
+	_, err = client.SensorDownload.DownloadSensorInstallerByID(
+		&sensor_download.DownloadSensorInstallerByIDParams{
+			ID:      *sensorResource.Sha256,
+			Context: ctx,
+		},
+		s.Limiter.Writer(ctx, io.MultiWriter(f, h, s.ProgressWriter)),
+	)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+
+	if err != nil {
+		return "", fmt.Errorf("failed to download sensor %s/%s from CrowdStrike API (size: %d bytes): %w",
+			s.OsShortName, s.OsVersion, *sensorResource.FileSize, err)
+	}
+
+	if sum := hex.EncodeToString(h.Sum(nil)); sum != *sensorResource.Sha256 {
+		return "", fmt.Errorf("downloaded sensor %s/%s sha256 %s does not match %s",
+			s.OsShortName, s.OsVersion, sum, *sensorResource.Sha256)
+	}
+
+	if err := os.Rename(partial, path); err != nil {
+		return "", fmt.Errorf("failed to move sensor into the cache: %w", err)
+	}
+
+	return path, nil
+}
+
+// upload uploads the cached installer to the object with a resumable upload.
+func (s *Sensor) upload(ctx context.Context, o *storage.ObjectHandle, path string) (*storage.ObjectAttrs, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	// cancelling the context aborts the upload, so an incomplete object is never created.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// the precondition makes the upload idempotent, so the storage client retries failed chunks
+	// and resumes the upload instead of failing it.
+	wc := o.If(storage.Conditions{DoesNotExist: true}).NewWriter(ctx)
+	if s.ChunkSize > 0 {
+		wc.ChunkSize = s.ChunkSize
+	}
+
+	if _, err := io.Copy(wc, io.TeeReader(s.Limiter.Reader(ctx, f), s.ProgressWriter)); err != nil {
+		cancel()
+		wc.Close()
+		return nil, err
+	}
+
+	if err := wc.Close(); err != nil {
+		return nil, err
+	}
+
+	return wc.Attrs(), nil
+}
+
+// fileSha256 returns the hex encoded sha256 of the file.
+func fileSha256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
 func (s *Sensor) determineBucketPath(sensorResource *models.DomainSensorInstallerV1) string {
 	version := *sensorResource.Version
 
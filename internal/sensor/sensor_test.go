@@ -1,11 +1,26 @@
 package sensor
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
 	"testing"
 
+	"cloud.google.com/go/storage"
+	"github.com/crowdstrike/gcp-os-policy/internal/progress"
 	"github.com/crowdstrike/gofalcon/falcon"
+	"github.com/crowdstrike/gofalcon/falcon/client"
+	"github.com/crowdstrike/gofalcon/falcon/client/sensor_download"
 	"github.com/crowdstrike/gofalcon/falcon/models"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/option"
 )
 
 func TestSensor_determineBucketPath(t *testing.T) {
@@ -169,3 +184,152 @@ func TestSensor_determineBucketPath(t *testing.T) {
 		})
 	}
 }
+
+// fakeSensorDownload serves a single installer with the content, counting the downloads.
+type fakeSensorDownload struct {
+	sensor_download.ClientService
+	installer *models.DomainSensorInstallerV1
+	content   string
+	downloads atomic.Int32
+}
+
+func newFakeSensorDownload(content string, sha string) *fakeSensorDownload {
+	size := int32(len(content))
+	return &fakeSensorDownload{
+		installer: &models.DomainSensorInstallerV1{
+			Name:     ptr("falcon-sensor-7.20.0-17306.el9.x86_64.rpm"),
+			Version:  ptr("7.20.17306"),
+			Sha256:   ptr(sha),
+			FileSize: &size,
+		},
+		content: content,
+	}
+}
+
+func (f *fakeSensorDownload) GetCombinedSensorInstallersByQuery(
+	_ *sensor_download.GetCombinedSensorInstallersByQueryParams,
+	_ ...sensor_download.ClientOption,
+) (*sensor_download.GetCombinedSensorInstallersByQueryOK, error) {
+	return &sensor_download.GetCombinedSensorInstallersByQueryOK{
+		Payload: &models.DomainSensorInstallersV1{Resources: []*models.DomainSensorInstallerV1{f.installer}},
+	}, nil
+}
+
+func (f *fakeSensorDownload) DownloadSensorInstallerByID(
+	_ *sensor_download.DownloadSensorInstallerByIDParams,
+	w io.Writer,
+	_ ...sensor_download.ClientOption,
+) (*sensor_download.DownloadSensorInstallerByIDOK, error) {
+	f.downloads.Add(1)
+	_, err := io.WriteString(w, f.content)
+	return &sensor_download.DownloadSensorInstallerByIDOK{}, err
+}
+
+func ptr(s string) *string {
+	return &s
+}
+
+func sha256Hex(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestSensor_download(t *testing.T) {
+	const content = "falcon sensor installer"
+	ctx := context.Background()
+
+	t.Run("cache miss and hit", func(t *testing.T) {
+		fake := newFakeSensorDownload(content, sha256Hex(content))
+		api := &client.CrowdStrikeAPISpecification{SensorDownload: fake}
+		s := &Sensor{CacheDir: t.TempDir(), ProgressWriter: progress.NewProgressWriter()}
+
+		path, err := s.download(ctx, api, fake.installer)
+		require.NoError(t, err)
+		assert.Equal(t, filepath.Join(s.CacheDir, sha256Hex(content)), path)
+		assert.FileExists(t, path)
+		assert.EqualValues(t, 1, fake.downloads.Load())
+
+		// the cached installer is reused.
+		_, err = s.download(ctx, api, fake.installer)
+		require.NoError(t, err)
+		assert.EqualValues(t, 1, fake.downloads.Load())
+
+		// a cached installer with a different sha256 is downloaded again.
+		require.NoError(t, os.WriteFile(path, []byte("truncated"), 0o600))
+		_, err = s.download(ctx, api, fake.installer)
+		require.NoError(t, err)
+		assert.EqualValues(t, 2, fake.downloads.Load())
+
+		cached, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, content, string(cached))
+	})
+
+	t.Run("sha256 mismatch", func(t *testing.T) {
+		fake := newFakeSensorDownload("tampered installer", sha256Hex(content))
+		api := &client.CrowdStrikeAPISpecification{SensorDownload: fake}
+		s := &Sensor{CacheDir: t.TempDir(), ProgressWriter: progress.NewProgressWriter()}
+
+		_, err := s.download(ctx, api, fake.installer)
+		assert.ErrorContains(t, err, "does not match")
+
+		// neither the partial nor the mismatched installer is left in the cache.
+		entries, err := os.ReadDir(s.CacheDir)
+		require.NoError(t, err)
+		assert.Empty(t, entries)
+	})
+}
+
+func TestSensor_StreamToBucketRetry(t *testing.T) {
+	const content = "falcon sensor installer"
+	ctx := context.Background()
+
+	// the first upload fails, the second completes.
+	var uploads atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.Method {
+		case http.MethodGet:
+			w.WriteHeader(http.StatusNotFound)
+			io.WriteString(w, `{"error": {"code": 404, "message": "Not Found"}}`)
+		case http.MethodPost:
+			io.Copy(io.Discard, r.Body)
+			if uploads.Add(1) == 1 {
+				w.WriteHeader(http.StatusBadRequest)
+				io.WriteString(w, `{"error": {"code": 400, "message": "upload failed"}}`)
+				return
+			}
+			io.WriteString(w, `{"bucket": "bucket", "name": "crowdstrike/falcon/linux/rhel/9/7.20.17306/falcon-sensor.rpm", "generation": "7"}`)
+		}
+	}))
+	defer srv.Close()
+
+	storageClient, err := storage.NewClient(
+		ctx,
+		option.WithEndpoint(srv.URL+"/storage/v1/"),
+		option.WithoutAuthentication(),
+	)
+	require.NoError(t, err)
+	defer storageClient.Close()
+
+	fake := newFakeSensorDownload(content, sha256Hex(content))
+	s := &Sensor{
+		Platform:     "linux",
+		Cloud:        falcon.CloudUs1,
+		BucketPrefix: "crowdstrike/falcon/linux/rhel/9",
+		CacheDir:     t.TempDir(),
+	}
+
+	require.NoError(t, s.StreamToBucket(ctx, &client.CrowdStrikeAPISpecification{SensorDownload: fake}, storageClient, "bucket"))
+
+	// the retried upload reuses the cached installer instead of downloading it again.
+	assert.EqualValues(t, 1, fake.downloads.Load())
+	assert.EqualValues(t, 2, uploads.Load())
+	assert.Equal(t, "bucket/crowdstrike/falcon/linux/rhel/9/7.20.17306/falcon-sensor.rpm", s.FullPath)
+	assert.EqualValues(t, 7, s.Generation)
+
+	// the cached installer is removed once it is uploaded.
+	entries, err := os.ReadDir(s.CacheDir)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
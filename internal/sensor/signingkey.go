@@ -0,0 +1,62 @@
+package sensor
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"cloud.google.com/go/storage"
+	"github.com/crowdstrike/gcp-os-policy/internal/logging"
+)
+
+const signingKeyPrefix = "crowdstrike/falcon/signing-keys"
+
+const armoredPublicKeyHeader = "-----BEGIN PGP PUBLIC KEY BLOCK-----"
+
+// UploadSigningKey uploads the ascii armored CrowdStrike signing key to the bucket and returns
+// its bucket/object path and generation. The object is named after the key's sha256 so an
+// existing key is reused and never replaced.
+func UploadSigningKey(
+	ctx context.Context,
+	storageClient *storage.Client,
+	bucket string,
+	key []byte,
+	logger *slog.Logger,
+) (string, int64, error) {
+	if !bytes.Contains(key, []byte(armoredPublicKeyHeader)) {
+		return "", 0, errors.New("the signing key must be an ascii armored PGP public key")
+	}
+
+	logger = logging.Or(logger)
+	sum := sha256.Sum256(key)
+	o := storageClient.Bucket(bucket).
+		Object(fmt.Sprintf("%s/%s.asc", signingKeyPrefix, hex.EncodeToString(sum[:])))
+
+	attrs, err := o.Attrs(ctx)
+	if errors.Is(err, storage.ErrObjectNotExist) {
+		wc := o.NewWriter(ctx)
+		wc.ContentType = "application/pgp-keys"
+
+		if _, err := wc.Write(key); err != nil {
+			wc.Close()
+			return "", 0, fmt.Errorf("failed to upload the signing key to bucket %s: %w", bucket, err)
+		}
+
+		if err := wc.Close(); err != nil {
+			return "", 0, fmt.Errorf("failed to upload the signing key to bucket %s: %w", bucket, err)
+		}
+
+		attrs = wc.Attrs()
+		logger.Info("signing key uploaded to bucket", "object", attrs.Name, "generation", attrs.Generation)
+	} else if err != nil {
+		return "", 0, fmt.Errorf("failed to check if the signing key exists in bucket %s: %w", bucket, err)
+	} else {
+		logger.Info("signing key already exists in bucket", "object", attrs.Name)
+	}
+
+	return fmt.Sprintf("%s/%s", attrs.Bucket, attrs.Name), attrs.Generation, nil
+}
@@ -0,0 +1,125 @@
+// Package throttle limits the bandwidth of readers and writers that share a Limiter.
+package throttle
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"golang.org/x/time/rate"
+)
+
+var bandwidthPattern = regexp.MustCompile(`^(\d+(?:\.\d+)?)\s*([KMG]?)(?:I?B)?(?:/S)?$`)
+
+var bandwidthUnits = map[string]float64{
+	"":  1,
+	"K": 1 << 10,
+	"M": 1 << 20,
+	"G": 1 << 30,
+}
+
+// ParseBandwidth parses a bandwidth in bytes per second with an optional K, M or G suffix,
+// e.g. 512K, 10M or 10MB/s. An empty string or 0 is unlimited, values below 1 byte per second are
+// rejected instead of being truncated to unlimited.
+func ParseBandwidth(s string) (int64, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	if s == "" {
+		return 0, nil
+	}
+
+	m := bandwidthPattern.FindStringSubmatch(s)
+	if m == nil {
+		return 0, fmt.Errorf("invalid bandwidth %q: must be bytes per second, e.g. 512K, 10M or 1G", s)
+	}
+
+	n, err := strconv.ParseFloat(m[1], 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid bandwidth %q: %w", s, err)
+	}
+
+	bytesPerSecond := int64(n * bandwidthUnits[m[2]])
+	if n > 0 && bytesPerSecond < 1 {
+		return 0, fmt.Errorf("invalid bandwidth %q: must be at least 1 byte per second, use 0 for unlimited", s)
+	}
+
+	return bytesPerSecond, nil
+}
+
+// Limiter caps the combined throughput of every reader and writer it wraps. A nil Limiter does not
+// limit anything.
+type Limiter struct {
+	limiter *rate.Limiter
+}
+
+// NewLimiter returns a Limiter for the bytes per second, or nil when bytesPerSecond is 0.
+func NewLimiter(bytesPerSecond int64) *Limiter {
+	if bytesPerSecond <= 0 {
+		return nil
+	}
+
+	// a burst of one second of traffic keeps the reads and writes reasonably sized.
+	return &Limiter{limiter: rate.NewLimiter(rate.Limit(bytesPerSecond), int(bytesPerSecond))}
+}
+
+// Reader returns r limited by l.
+func (l *Limiter) Reader(ctx context.Context, r io.Reader) io.Reader {
+	if l == nil {
+		return r
+	}
+	return &reader{ctx: ctx, r: r, limiter: l.limiter}
+}
+
+// Writer returns w limited by l.
+func (l *Limiter) Writer(ctx context.Context, w io.Writer) io.Writer {
+	if l == nil {
+		return w
+	}
+	return &writer{ctx: ctx, w: w, limiter: l.limiter}
+}
+
+type reader struct {
+	ctx     context.Context
+	r       io.Reader
+	limiter *rate.Limiter
+}
+
+func (r *reader) Read(p []byte) (int, error) {
+	if burst := r.limiter.Burst(); len(p) > burst {
+		p = p[:burst]
+	}
+
+	n, err := r.r.Read(p)
+	if n > 0 {
+		if werr := r.limiter.WaitN(r.ctx, n); werr != nil {
+			return n, werr
+		}
+	}
+	return n, err
+}
+
+type writer struct {
+	ctx     context.Context
+	w       io.Writer
+	limiter *rate.Limiter
+}
+
+func (w *writer) Write(p []byte) (int, error) {
+	var written int
+	for len(p) > 0 {
+		chunk := p[:min(len(p), w.limiter.Burst())]
+		if err := w.limiter.WaitN(w.ctx, len(chunk)); err != nil {
+			return written, err
+		}
+
+		n, err := w.w.Write(chunk)
+		written += n
+		if err != nil {
+			return written, err
+		}
+		p = p[n:]
+	}
+	return written, nil
+}
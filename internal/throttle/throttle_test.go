@@ -0,0 +1,68 @@
+package throttle
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseBandwidth(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+		wantErr  bool
+	}{
+		{input: "", expected: 0},
+		{input: "0", expected: 0},
+		{input: "2048", expected: 2048},
+		{input: "512K", expected: 512 << 10},
+		{input: "10M", expected: 10 << 20},
+		{input: "10mb/s", expected: 10 << 20},
+		{input: "1.5MiB", expected: 3 << 19},
+		{input: "1G", expected: 1 << 30},
+		{input: "10Mbps", wantErr: true},
+		{input: "-1M", wantErr: true},
+		{input: "0.5", wantErr: true},
+		{input: "0.0", expected: 0},
+		{input: "0.5K", expected: 512},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			n, err := ParseBandwidth(tt.input)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, n)
+		})
+	}
+}
+
+func TestLimiter(t *testing.T) {
+	ctx := context.Background()
+	data := strings.Repeat("a", 100)
+
+	assert.Nil(t, NewLimiter(0))
+
+	// a nil limiter passes the reader and writer through.
+	var nilLimiter *Limiter
+	r := strings.NewReader(data)
+	assert.Same(t, r, nilLimiter.Reader(ctx, r))
+
+	// reads and writes larger than the burst are split into bursts.
+	l := NewLimiter(1 << 20)
+	l.limiter.SetBurst(16)
+
+	var buf bytes.Buffer
+	n, err := io.Copy(l.Writer(ctx, &buf), l.Reader(ctx, strings.NewReader(data)))
+	require.NoError(t, err)
+	assert.EqualValues(t, len(data), n)
+	assert.Equal(t, data, buf.String())
+}
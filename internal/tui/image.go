@@ -0,0 +1,84 @@
+package tui
+
+import (
+	"math"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/progress"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/crowdstrike/gcp-os-policy/internal/sensor"
+	"github.com/muesli/termenv"
+)
+
+type ImageSyncModel struct {
+	width   int
+	Image   *sensor.Image
+	total   int64
+	written int64
+	done    bool
+}
+
+func (m ImageSyncModel) Init() tea.Cmd {
+	if termenv.HasDarkBackground() {
+		progressBarColor = "#F2F3F2"
+	}
+
+	return tick()
+}
+
+func (m ImageSyncModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "ctrl+c":
+			return m, tea.Quit
+		}
+
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+		return m, nil
+
+	case tickMsg:
+		pw := m.Image.ProgressWriter
+		if pw != nil {
+			m.total = pw.Total()
+			m.written = pw.N()
+			m.done = m.total > 0 && pw.Done()
+		}
+
+		if !m.done {
+			return m, tick()
+		}
+
+		return m, tea.Quit
+	}
+
+	return m, nil
+}
+
+func (m ImageSyncModel) View() string {
+	s := strings.Builder{}
+
+	prefix := "Staging node sensor image to Artifact Registry... "
+	s.WriteString(prefix)
+
+	percent := float64(m.written) / float64(m.total)
+
+	if math.IsNaN(percent) || math.IsInf(percent, 0) {
+		percent = 0
+	}
+
+	if m.done {
+		percent = 100
+	}
+
+	s.WriteString(
+		progress.New(progress.WithWidth((m.width-len(prefix))/2), progress.WithSolidFill(progressBarColor)).
+			ViewAs(percent),
+	)
+
+	s.WriteString("\n")
+
+	return s.String()
+}
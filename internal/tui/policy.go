@@ -15,6 +15,9 @@ type PolicyModel struct {
 	spinner   spinner.Model
 
 	Assignments []*policy.Assignment
+	Rollout     policy.Rollout
+	// Waves are set when assignments are rolled out in waves.
+	Waves []*policy.Wave
 }
 
 func NewPolicyModel() PolicyModel {
@@ -23,6 +26,7 @@ func NewPolicyModel() PolicyModel {
 
 	return PolicyModel{
 		spinner: s,
+		Rollout: policy.RolloutProfiles[policy.DefaultRolloutProfile],
 	}
 }
 
@@ -49,6 +53,13 @@ func (m PolicyModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m, cmd
 
 	case tickMsg:
+		if len(m.Waves) > 0 {
+			if wavesFinished(m.Waves) {
+				return m, tea.Quit
+			}
+			return m, tick()
+		}
+
 		var completed int
 
 		for _, assignment := range m.Assignments {
@@ -72,30 +83,151 @@ func (m PolicyModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 func (m PolicyModel) View() string {
 	s := strings.Builder{}
 
-	for _, a := range m.Assignments {
+	if len(m.Waves) > 0 {
+		s.WriteString(m.wavesView())
+	} else {
+		s.WriteString(m.assignmentsView())
+	}
+
+	// if m.completed != len(m.Assignments) && !m.Assignments[0].SkipWait {
+	prefix := Yellow(WarningIcon)
+	notice := fmt.Sprintf(
+		"\n\n%s %s This may take a while depending on your rollout settings and number of instances. You can use --skip-wait to create the assignments without waiting for the rollout to complete.",
+		prefix,
+		m.Rollout.Describe(),
+	)
+	s.WriteString(DefaultStyle.Width(m.width).PaddingLeft(2).Render(notice))
+	// }
+
+	s.WriteString("\n\n")
+	return s.String()
+}
+
+func (m PolicyModel) assignmentsView() string {
+	s := strings.Builder{}
+
+	grouped := multipleProjects(m.Assignments)
+	indent := "  "
+	if grouped {
+		indent = "    "
+	}
+
+	for i, a := range m.Assignments {
+		if grouped && (i == 0 || m.Assignments[i-1].Project != a.Project) {
+			s.WriteString(fmt.Sprintf("  %s\n", ProjectName(a.Project)))
+		}
+
 		var line string
 		if a.Done() {
 			icon := Green(SuccessIcon)
 			if a.Failed() {
 				icon = Red(FailIcon)
 			}
-			line = fmt.Sprintf("  %s %s\n", icon, a.Zone)
+			line = fmt.Sprintf("%s%s %s\n", indent, icon, a.Zone)
 		} else {
-			line = fmt.Sprintf("  %s %s\n", m.spinner.View(), a.Zone)
+			line = fmt.Sprintf("%s%s %s\n", indent, m.spinner.View(), a.Zone)
 		}
 
 		s.WriteString(line)
 	}
 
-	// if m.completed != len(m.Assignments) && !m.Assignments[0].SkipWait {
-	prefix := Yellow(WarningIcon)
-	notice := fmt.Sprintf(
-		"\n\n%s This may take a while depending on your rollout settings and number of instances. You can use --skip-wait to create the assignments without waiting for the rollout to complete.",
-		prefix,
-	)
-	s.WriteString(DefaultStyle.Width(m.width).PaddingLeft(2).Render(notice))
-	// }
+	return s.String()
+}
+
+func (m PolicyModel) wavesView() string {
+	s := strings.Builder{}
+
+	var all []*policy.Assignment
+	for _, w := range m.Waves {
+		all = append(all, w.Assignments...)
+	}
+	grouped := multipleProjects(all)
+
+	for _, w := range m.Waves {
+		label := fmt.Sprintf("Wave %d/%d", w.Number, len(m.Waves))
+		if w.Canary {
+			label += " (canary)"
+		}
+
+		compliant, nonCompliant, total := w.Compliance()
+
+		switch w.Status() {
+		case policy.WavePending:
+			s.WriteString(fmt.Sprintf("  - %s waiting\n", label))
+		case policy.WaveRollingOut:
+			s.WriteString(fmt.Sprintf("  %s %s rolling out\n", m.spinner.View(), label))
+		case policy.WaveVerifying:
+			s.WriteString(fmt.Sprintf(
+				"  %s %s verifying compliance: %d/%d compliant, %d non-compliant\n",
+				m.spinner.View(),
+				label,
+				compliant,
+				total,
+				nonCompliant,
+			))
+		case policy.WaveSucceeded:
+			s.WriteString(fmt.Sprintf(
+				"  %s %s complete: %d/%d compliant\n",
+				Green(SuccessIcon),
+				label,
+				compliant,
+				total,
+			))
+		case policy.WaveFailed:
+			s.WriteString(fmt.Sprintf("  %s %s stopped: %s\n", Red(FailIcon), label, w.Message()))
+		}
+
+		for _, a := range w.Assignments {
+			name := a.Zone
+			if grouped {
+				name = fmt.Sprintf("%s/%s", ProjectName(a.Project), a.Zone)
+			}
+
+			icon := " "
+			if a.Done() {
+				icon = Green(SuccessIcon)
+				if a.Failed() {
+					icon = Red(FailIcon)
+				}
+			} else if w.Status() == policy.WaveRollingOut {
+				icon = m.spinner.View()
+			}
+
+			s.WriteString(fmt.Sprintf("      %s %s\n", icon, name))
+		}
+	}
 
-	s.WriteString("\n\n")
 	return s.String()
 }
+
+// wavesFinished returns true once every wave succeeded or any wave failed.
+func wavesFinished(waves []*policy.Wave) bool {
+	for _, w := range waves {
+		switch w.Status() {
+		case policy.WaveFailed:
+			return true
+		case policy.WaveSucceeded:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// ProjectName returns a display name for a project, accounting for the gcloud cli's active project.
+func ProjectName(project string) string {
+	if project == "" {
+		return "(active gcloud project)"
+	}
+	return project
+}
+
+// multipleProjects returns true if the assignments target more than one project.
+func multipleProjects(assignments []*policy.Assignment) bool {
+	for _, a := range assignments {
+		if a.Project != assignments[0].Project {
+			return true
+		}
+	}
+	return false
+}
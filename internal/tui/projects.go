@@ -0,0 +1,22 @@
+package tui
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/crowdstrike/gcp-os-policy/internal/gcputil"
+)
+
+// WarnFailedProjects prints the projects gcputil.ResolveTargets skipped. The warnings go to stderr
+// so they do not mix with json or csv output.
+func WarnFailedProjects(err error) {
+	for _, pe := range gcputil.FailedProjects(err) {
+		fmt.Fprintf(
+			os.Stderr,
+			"%s Unable to resolve the zones of project %s, skipping: %s\n",
+			Yellow(WarningIcon),
+			pe.Project,
+			pe.Err,
+		)
+	}
+}
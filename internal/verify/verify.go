@@ -0,0 +1,127 @@
+// Package verify cross-checks compute instances against the hosts in Falcon.
+package verify
+
+import (
+	"strings"
+	"time"
+
+	"github.com/crowdstrike/gcp-os-policy/internal/falconutil"
+	"github.com/crowdstrike/gcp-os-policy/internal/gcputil"
+	"github.com/crowdstrike/gcp-os-policy/internal/policy"
+)
+
+const (
+	// StatusOK is an instance whose host was seen recently.
+	StatusOK = "ok"
+	// StatusMissing is an instance without a host in Falcon.
+	StatusMissing = "missing"
+	// StatusStale is an instance whose host has not been seen recently.
+	StatusStale = "stale"
+)
+
+// Result is the Falcon host of a compute instance.
+type Result struct {
+	Project    string     `json:"project"`
+	Zone       string     `json:"zone"`
+	Instance   string     `json:"instance"`
+	InstanceID string     `json:"instanceId"`
+	Status     string     `json:"status"`
+	AID        string     `json:"aid,omitempty"`
+	LastSeen   *time.Time `json:"lastSeen,omitempty"`
+}
+
+// Match finds the Falcon host of each instance. Hosts are matched by instance id, falling back
+// to the hostname when the sensor does not report the instance id. Hosts reporting a different
+// project or zone are not matched by hostname. When several hosts match, e.g. after the sensor
+// was reinstalled, the most recently seen host is used.
+//
+// Hosts not seen within staleAfter of now are reported as stale.
+func Match(
+	instances []gcputil.Instance,
+	hosts []falconutil.Host,
+	staleAfter time.Duration,
+	now time.Time,
+) []Result {
+	byID := map[string]falconutil.Host{}
+	byHostname := map[string][]falconutil.Host{}
+	for _, h := range hosts {
+		if h.InstanceID != "" {
+			if prev, ok := byID[h.InstanceID]; !ok || h.LastSeen.After(prev.LastSeen) {
+				byID[h.InstanceID] = h
+			}
+			continue
+		}
+
+		if name := shortHostname(h.Hostname); name != "" {
+			byHostname[name] = append(byHostname[name], h)
+		}
+	}
+
+	results := make([]Result, 0, len(instances))
+	for _, i := range instances {
+		r := Result{
+			Project:    i.Project,
+			Zone:       i.Zone,
+			Instance:   i.Name,
+			InstanceID: i.ID,
+			Status:     StatusMissing,
+		}
+
+		h, ok := byID[i.ID]
+		if !ok {
+			h, ok = matchHostname(i, byHostname)
+		}
+
+		if ok {
+			r.AID = h.AID
+			r.LastSeen = &h.LastSeen
+			r.Status = StatusOK
+			if now.Sub(h.LastSeen) > staleAfter {
+				r.Status = StatusStale
+			}
+		}
+
+		results = append(results, r)
+	}
+
+	return results
+}
+
+// Filter returns the instances the OS Policy applies to. Container-Optimized OS VMs, which the
+// policy can not install a sensor on, and VMs whose labels the filter does not select are dropped.
+func Filter(instances []gcputil.Instance, filter policy.InstanceFilter) []gcputil.Instance {
+	var filtered []gcputil.Instance
+	for _, i := range instances {
+		if i.COS || !filter.MatchesLabels(i.Labels) {
+			continue
+		}
+		filtered = append(filtered, i)
+	}
+	return filtered
+}
+
+func matchHostname(i gcputil.Instance, byHostname map[string][]falconutil.Host) (falconutil.Host, bool) {
+	var match falconutil.Host
+	var ok bool
+
+	for _, name := range []string{i.Name, shortHostname(i.Hostname)} {
+		for _, h := range byHostname[strings.ToLower(name)] {
+			if (h.Project != "" && h.Project != i.Project) || (h.Zone != "" && h.Zone != i.Zone) {
+				continue
+			}
+
+			if !ok || h.LastSeen.After(match.LastSeen) {
+				match, ok = h, true
+			}
+		}
+	}
+
+	return match, ok
+}
+
+// shortHostname returns the lower case host name without the domain, e.g. vm-1 for
+// vm-1.us-central1-a.c.project.internal.
+func shortHostname(hostname string) string {
+	name, _, _ := strings.Cut(hostname, ".")
+	return strings.ToLower(name)
+}
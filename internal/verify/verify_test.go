@@ -0,0 +1,109 @@
+package verify
+
+import (
+	"testing"
+	"time"
+
+	"github.com/crowdstrike/gcp-os-policy/internal/falconutil"
+	"github.com/crowdstrike/gcp-os-policy/internal/gcputil"
+	"github.com/crowdstrike/gcp-os-policy/internal/policy"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatch(t *testing.T) {
+	now := time.Date(2025, 1, 2, 12, 0, 0, 0, time.UTC)
+	recent := now.Add(-time.Hour)
+	old := now.Add(-72 * time.Hour)
+
+	instances := []gcputil.Instance{
+		{Project: "project", Zone: "us-central1-a", Name: "by-id", ID: "1"},
+		{Project: "project", Zone: "us-central1-a", Name: "by-name", ID: "2"},
+		{Project: "project", Zone: "us-central1-a", Name: "custom", ID: "3", Hostname: "web.example.com"},
+		{Project: "project", Zone: "us-central1-a", Name: "stale", ID: "4"},
+		{Project: "project", Zone: "us-central1-a", Name: "reinstalled", ID: "5"},
+		{Project: "project", Zone: "us-central1-b", Name: "other-zone", ID: "6"},
+		{Project: "project", Zone: "us-central1-a", Name: "missing", ID: "7"},
+	}
+
+	hosts := []falconutil.Host{
+		{AID: "a1", InstanceID: "1", Hostname: "unrelated", LastSeen: recent},
+		{AID: "a2", Hostname: "BY-NAME.us-central1-a.c.project.internal", LastSeen: recent},
+		{AID: "a3", Hostname: "web", Project: "project", LastSeen: recent},
+		{AID: "a4", InstanceID: "4", LastSeen: old},
+		{AID: "a5-old", InstanceID: "5", LastSeen: old},
+		{AID: "a5", InstanceID: "5", LastSeen: recent},
+		{AID: "a6", Hostname: "other-zone", Zone: "us-central1-a", LastSeen: recent},
+	}
+
+	want := map[string][2]string{
+		"by-id":       {StatusOK, "a1"},
+		"by-name":     {StatusOK, "a2"},
+		"custom":      {StatusOK, "a3"},
+		"stale":       {StatusStale, "a4"},
+		"reinstalled": {StatusOK, "a5"},
+		"other-zone":  {StatusMissing, ""},
+		"missing":     {StatusMissing, ""},
+	}
+
+	results := Match(instances, hosts, 24*time.Hour, now)
+	assert.Len(t, results, len(instances))
+	for _, r := range results {
+		assert.Equal(t, want[r.Instance], [2]string{r.Status, r.AID}, r.Instance)
+		if r.AID == "" {
+			assert.Nil(t, r.LastSeen, r.Instance)
+		}
+	}
+}
+
+func TestFilter(t *testing.T) {
+	instances := []gcputil.Instance{
+		{Name: "prod", Labels: map[string]string{"env": "prod"}},
+		{Name: "prod-excluded", Labels: map[string]string{"env": "prod", "falcon": "skip"}},
+		{Name: "dev", Labels: map[string]string{"env": "dev"}},
+		{Name: "unlabeled"},
+		{Name: "gke-node", Labels: map[string]string{"env": "prod"}, COS: true},
+	}
+
+	names := func(instances []gcputil.Instance) []string {
+		var names []string
+		for _, i := range instances {
+			names = append(names, i.Name)
+		}
+		return names
+	}
+
+	tests := []struct {
+		name   string
+		filter policy.InstanceFilter
+		want   []string
+	}{
+		{
+			name: "no labels",
+			want: []string{"prod", "prod-excluded", "dev", "unlabeled"},
+		},
+		{
+			name:   "inclusion",
+			filter: policy.InstanceFilter{InclusionLabels: policy.ParseLabelSets([]string{"env:prod"})},
+			want:   []string{"prod", "prod-excluded"},
+		},
+		{
+			name:   "exclusion",
+			filter: policy.InstanceFilter{ExclusionLabels: policy.ParseLabelSets([]string{"falcon:skip", "env:dev"})},
+			want:   []string{"prod", "unlabeled"},
+		},
+		{
+			name: "inclusion and exclusion",
+			filter: policy.InstanceFilter{
+				InclusionLabels: policy.ParseLabelSets([]string{"env:prod", "env:dev"}),
+				ExclusionLabels: policy.ParseLabelSets([]string{"falcon:skip"}),
+			},
+			want: []string{"prod", "dev"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, names(Filter(instances, tt.filter)))
+		})
+	}
+}
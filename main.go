@@ -5,6 +5,9 @@ package main
 
 import "github.com/crowdstrike/gcp-os-policy/pkg/cmd/root"
 
+// version is set at build time by goreleaser.
+var version = "dev"
+
 func main() {
-	root.Execute()
+	root.Execute(version)
 }
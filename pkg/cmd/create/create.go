@@ -4,38 +4,91 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"log/slog"
+	"net/http"
 	"os"
 	"path/filepath"
-	"sort"
+	"strings"
+	"sync"
+	"time"
 
 	"cloud.google.com/go/storage"
 	"github.com/MakeNowJust/heredoc"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/huh"
+	"github.com/crowdstrike/gcp-os-policy/internal/config"
+	"github.com/crowdstrike/gcp-os-policy/internal/doctor"
 	"github.com/crowdstrike/gcp-os-policy/internal/errorsutil"
 	"github.com/crowdstrike/gcp-os-policy/internal/falconutil"
+	"github.com/crowdstrike/gcp-os-policy/internal/gcputil"
+	"github.com/crowdstrike/gcp-os-policy/internal/logging"
 	"github.com/crowdstrike/gcp-os-policy/internal/policy"
 	"github.com/crowdstrike/gcp-os-policy/internal/prompt"
 	"github.com/crowdstrike/gcp-os-policy/internal/sensor"
+	"github.com/crowdstrike/gcp-os-policy/internal/throttle"
 	"github.com/crowdstrike/gcp-os-policy/internal/tui"
+	"github.com/crowdstrike/gcp-os-policy/internal/verify"
 	"github.com/crowdstrike/gofalcon/falcon"
+	"github.com/crowdstrike/gofalcon/falcon/client"
 	"github.com/spf13/cobra"
 	"golang.org/x/sync/errgroup"
 )
 
+// wavePollInterval is how often os config compliance reports are checked during a wave.
+const wavePollInterval = 30 * time.Second
+
 var falconClientId string
 var falconClientSecret string
 var falconCloud string
 var falconCid string
 var linuxInstallParams string
 var windowsInstallParams string
+var sensorTags []string
+var proxyHost string
+var proxyPort int
+var disableProxy bool
+var provisioningToken string
+var provisioningTokenSecret string
+var sensorBackend string
+var billing string
 var storageBucket string
 var outputDir string
+var outputFile string
+var outputFormat string
+var configFile string
+var templateFile string
+var gpgKeyFile string
+var skipGPGVerify bool
 var zones []string
+var regions []string
+var allZones bool
+var zonesWithInstances bool
+var project string
+var projects []string
+var projectsFile string
 var skipWait bool
 var inclusionLabels []string
 var exclusionLabels []string
-var debug bool
+var runDoctor bool
+var rolloutProfile string
+var disruptionBudget string
+var minWaitDuration string
+var mode string
+var enforceVersion bool
+var uninstall bool
+var maintenanceToken string
+var fetchMaintenanceToken bool
+var maintenanceTokenSecret string
+var maintenanceTokenAids []string
+var rolloutWaves int
+var canaryZones []string
+var waveComplianceThreshold float64
+var waveFailureLimit float64
+var waveTimeout time.Duration
+var concurrency int
+var maxBandwidth string
+var chunkSize int
+var cacheDir string
 
 // createCmd represents the base cs-policy create when called without any subcommands
 var createCmd = &cobra.Command{
@@ -50,7 +103,16 @@ var createCmd = &cobra.Command{
     - Create OS Policy Assignments in the targeted zones`,
 	Example: heredoc.Doc(`
     Target all VMs in the us-central1-a and us-central-b zones
-    $ cs-policy create --zones=us-central1-a,us-central-b --bucket=my-bucket
+    $ cs-policy create --zones=us-central1-a,us-central-b --bucket=my-bucket --gpg-key=falcon-sensor.asc
+
+    Target all VMs in every zone of the us-central1 and europe-west4 regions that currently has VMs
+    $ cs-policy create --regions=us-central1,europe-west4 --zones-with-instances --bucket=my-bucket
+
+    Target all VMs in the us-central1-a zone of several projects
+    $ cs-policy create --zones=us-central1-a --bucket=my-bucket --projects=project-a,project-b
+
+    Remove the sensor from all VMs in the us-central1-a zone
+    $ cs-policy create --uninstall --zones=us-central1-a --maintenance-token=$FALCON_MAINTENANCE_TOKEN
 
     Target all VMs in the us-central1-a zone with custom install parameters
     $ cs-policy create --bucket example-bucket --zone us-central1-a --linux-install-params='--tags="Washington/DC_USA,Production" --aph=proxy.example.com --app=8080' --windows-install-params='GROUPING_TAGS="Washington/DC_USA,Production" APP_PROXYNAME=proxy.example.com APP_PROXYPORT=8080'
@@ -58,6 +120,7 @@ var createCmd = &cobra.Command{
 	Args: cobra.ExactArgs(0),
 	Run: func(_ *cobra.Command, _ []string) {
 		var err error
+		logger := slog.Default()
 
 		// Start output with new line.
 		fmt.Println("")
@@ -110,7 +173,7 @@ var createCmd = &cobra.Command{
 			}
 		}
 
-		if storageBucket == "" {
+		if storageBucket == "" && !uninstall {
 			storageBucket, err = prompt.PromptOutputBucket()
 			if err != nil {
 				if errors.Is(huh.ErrUserAborted, err) {
@@ -122,264 +185,354 @@ var createCmd = &cobra.Command{
 			}
 		}
 
-		ac := falcon.ApiConfig{
-			ClientId:          falconClientId,
-			ClientSecret:      falconClientSecret,
-			Cloud:             cloud,
-			Context:           context.Background(),
-			UserAgentOverride: "crowdstrike-gcp-vm-manager-os-policy/v0.0.2",
-			Debug:             debug,
+		rollout, err := policy.NewRollout(rolloutProfile, disruptionBudget, minWaitDuration)
+		if err != nil {
+			fmt.Println(err)
+			return
 		}
 
-		client, err := falcon.NewClient(&ac)
+		if err := validateWaveFlags(); err != nil {
+			fmt.Println(err)
+			return
+		}
 
+		limiter, err := validateTransferFlags()
 		if err != nil {
-			fmt.Println(
-				errorsutil.DefaultError("Unexpected error while creating falcon client.", err),
-			)
+			fmt.Println(err)
 			return
 		}
 
-		if falconCid == "" {
-			fmt.Println("No cid provided, grabbing cid...")
+		policyMode, err := policy.ParseMode(mode)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
 
-			cid, err := falconutil.CID(client)
+		policyFormat, err := policy.ParseFormat(outputFormat)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+
+		cfg, err := config.Load(configFile)
+		if err != nil {
+			fmt.Println(errorsutil.DefaultError("Unable to read the config file.", err))
+			return
+		}
+
+		if linuxInstallParams == "" {
+			linuxInstallParams = cfg.InstallParams[policy.PlatformLinux]
+		}
+
+		if windowsInstallParams == "" {
+			windowsInstallParams = cfg.InstallParams[policy.PlatformWindows]
+		}
+
+		var customTemplate []byte
+		if templateFile != "" {
+			customTemplate, err = os.ReadFile(templateFile)
 			if err != nil {
 				fmt.Println(
-					errorsutil.DefaultError("Unexpected error while grabbing cid.", err),
+					errorsutil.DefaultError(
+						fmt.Sprintf("Unable to read the GCP OS Policy template (%s).", templateFile),
+						err,
+					),
 				)
 				return
 			}
 
-			fmt.Printf("Using cid: %s\n", cid)
-			falconCid = cid
+			if len(cfg.Hooks) > 0 {
+				fmt.Printf(
+					"%s Hooks from the config file are not added to custom templates (%s).\n",
+					tui.Yellow(tui.WarningIcon),
+					templateFile,
+				)
+			}
 		}
 
-		targetSensors := []sensor.Sensor{
-			{
-				Filter:       "os:'*RHEL*'+os_version:'7'+platform:'linux'",
-				OsShortName:  "rhel",
-				OsVersion:    "7*",
-				Platform:     "linux",
-				Cloud:        cloud,
-				BucketPrefix: fmt.Sprintf("crowdstrike/falcon/%s/linux/rhel/7", ac.Cloud.String()),
-			},
-			{
-				Filter:       "os:'*RHEL*'+os_version:'8'+platform:'linux'",
-				OsShortName:  "rhel",
-				OsVersion:    "8*",
-				Platform:     "linux",
-				Cloud:        cloud,
-				BucketPrefix: fmt.Sprintf("crowdstrike/falcon/%s/linux/rhel/8", ac.Cloud.String()),
-			},
-			{
-				Filter:       "os:'*RHEL*'+os_version:'9'+platform:'linux'",
-				OsShortName:  "rhel",
-				OsVersion:    "9*",
-				Platform:     "linux",
-				Cloud:        cloud,
-				BucketPrefix: fmt.Sprintf("crowdstrike/falcon/%s/linux/rhel/9", ac.Cloud.String()),
-			},
-			{
-				Filter:       "os:'*RHEL*'+os_version:'10'+platform:'linux'",
-				OsShortName:  "rhel",
-				OsVersion:    "10*",
-				Platform:     "linux",
-				Cloud:        cloud,
-				BucketPrefix: fmt.Sprintf("crowdstrike/falcon/%s/linux/rhel/10", ac.Cloud.String()),
-			},
-			{
-				Filter:      "os:'*CentOS*'+os_version:'8'+platform:'linux'",
-				OsShortName: "centos",
-				OsVersion:   "8*",
-				Platform:    "linux",
-				Cloud:       cloud,
-				BucketPrefix: fmt.Sprintf(
-					"crowdstrike/falcon/%s/linux/centos/8",
-					ac.Cloud.String(),
-				),
-			},
-			{
-				Filter:      "os:'*CentOS Stream*'+os_version:'9'+platform:'linux'",
-				OsShortName: "centos",
-				OsVersion:   "9*",
-				Platform:    "linux",
-				Cloud:       cloud,
-				BucketPrefix: fmt.Sprintf(
-					"crowdstrike/falcon/%s/linux/centos/9",
-					ac.Cloud.String(),
-				),
-			},
-			{
-				Filter:      "os:'*CentOS Stream*'+os_version:'10'+platform:'linux'",
-				OsShortName: "centos",
-				OsVersion:   "10*",
-				Platform:    "linux",
-				Cloud:       cloud,
-				BucketPrefix: fmt.Sprintf(
-					"crowdstrike/falcon/%s/linux/centos/10",
-					ac.Cloud.String(),
-				),
-			},
-			{
-				Filter:      "os:'*SLES*'+os_version:'12'+platform:'linux'",
-				OsShortName: "sles",
-				OsVersion:   "12*",
-				Platform:    "linux",
-				Cloud:       cloud,
-				BucketPrefix: fmt.Sprintf(
-					"crowdstrike/falcon/%s/linux/sles/12",
-					ac.Cloud.String(),
-				),
-			},
-			{
-				Filter:      "os:'*SLES*'+os_version:'15'+platform:'linux'",
-				OsShortName: "sles",
-				OsVersion:   "15*",
-				Platform:    "linux",
-				Cloud:       cloud,
-				BucketPrefix: fmt.Sprintf(
-					"crowdstrike/falcon/%s/linux/sles/15",
-					ac.Cloud.String(),
-				),
-			},
-			{
-				Filter:      "os:'*Ubuntu*'+os_version:'*16/18/20/22/24*'+os_version:!'*arm64*'+os_version:!~'zLinux'+platform:'linux'",
-				OsShortName: "ubuntu",
-				Platform:    "linux",
-				Cloud:       cloud,
-				BucketPrefix: fmt.Sprintf(
-					"crowdstrike/falcon/%s/linux/ubuntu",
-					ac.Cloud.String(),
-				),
-			},
-			{
-				Filter:      "os:'Debian'+os_version:'*9/10/11/12/13*'+os_version:!'*arm64*'+platform:'linux'",
-				OsShortName: "debian",
-				Platform:    "linux",
-				Cloud:       cloud,
-				BucketPrefix: fmt.Sprintf(
-					"crowdstrike/falcon/%s/linux/debian",
-					ac.Cloud.String(),
-				),
-			},
-			{
-				Filter:      "os:'Windows'+platform:'windows'",
-				OsShortName: "windows",
-				Platform:    "windows",
-				Cloud:       cloud,
-				BucketPrefix: fmt.Sprintf(
-					"crowdstrike/falcon/%s/windows",
-					ac.Cloud.String(),
-				),
-			},
-			{
-				Filter:      "os:'*Oracle*'+os_version:'7'+platform:'linux'",
-				OsShortName: "ol",
-				OsVersion:   "7*",
-				Platform:    "linux",
-				Cloud:       cloud,
-				BucketPrefix: fmt.Sprintf(
-					"crowdstrike/falcon/%s/linux/oracle/7",
-					ac.Cloud.String(),
-				),
-			},
-			{
-				Filter:      "os:'*Oracle*'+os_version:'8'+platform:'linux'",
-				OsShortName: "ol",
-				OsVersion:   "8*",
-				Platform:    "linux",
-				Cloud:       cloud,
-				BucketPrefix: fmt.Sprintf(
-					"crowdstrike/falcon/%s/linux/oracle/8",
-					ac.Cloud.String(),
-				),
-			},
-			{
-				Filter:      "os:'*Oracle*'+os_version:'9'+platform:'linux'",
-				OsShortName: "ol",
-				OsVersion:   "9*",
-				Platform:    "linux",
-				Cloud:       cloud,
-				BucketPrefix: fmt.Sprintf(
-					"crowdstrike/falcon/%s/linux/oracle/9",
-					ac.Cloud.String(),
-				),
-			},
-			{
-				Filter:      "os:'*Oracle*'+os_version:'10'+platform:'linux'",
-				OsShortName: "ol",
-				OsVersion:   "10*",
-				Platform:    "linux",
-				Cloud:       cloud,
-				BucketPrefix: fmt.Sprintf(
-					"crowdstrike/falcon/%s/linux/oracle/10",
-					ac.Cloud.String(),
-				),
-			},
+		var signingKey []byte
+		switch {
+		case uninstall, policyMode == policy.ModeValidation:
+		case skipGPGVerify:
+			fmt.Printf(
+				"%s --skip-gpg-verify is set, the Linux installers are installed without verifying their signature.\n",
+				tui.Yellow(tui.WarningIcon),
+			)
+		case gpgKeyFile != "":
+			signingKey, err = os.ReadFile(gpgKeyFile)
+			if err != nil {
+				fmt.Println(
+					errorsutil.DefaultError(
+						fmt.Sprintf("Unable to read the signing key (%s).", gpgKeyFile),
+						err,
+					),
+				)
+				return
+			}
+		default:
+			fmt.Println("The Linux installers are verified with the CrowdStrike signing key before they are installed. Provide the key with --gpg-key or FALCON_GPG_KEY, or use --skip-gpg-verify to install without verifying signatures on legacy images.")
+			return
 		}
 
-		var storageSyncModel tui.StorageSyncModel
-		var sensors []*sensor.Sensor
+		sensorOptions := policy.SensorOptions{
+			Tags:                    sensorTags,
+			ProxyHost:               proxyHost,
+			ProxyPort:               proxyPort,
+			DisableProxy:            disableProxy,
+			ProvisioningToken:       provisioningToken,
+			Backend:                 sensorBackend,
+			Billing:                 billing,
+			ProvisioningTokenSecret: provisioningTokenSecret,
+		}
 
-		storageClient, err := storage.NewClient(context.Background())
+		if err := sensorOptions.Validate(); err != nil {
+			fmt.Println(err)
+			return
+		}
 
+		validationMode := policyMode == policy.ModeValidation
+		assignmentPrefix := policy.AssignmentPrefix(policyMode)
+
+		if uninstall {
+			if validationMode {
+				fmt.Println("--uninstall can not be used with --mode=validation.")
+				return
+			}
+			assignmentPrefix = policy.DecommissionAssignmentPrefix
+		}
+
+		if validationMode && rolloutWaves > 0 {
+			fmt.Println("--rollout-waves can not be used with --mode=validation, VMs missing the sensor are expected to be non-compliant.")
+			return
+		}
+
+		deployProjects, err := gcputil.TargetProjects(append([]string{project}, projects...), projectsFile)
 		if err != nil {
 			fmt.Println(
-				errorsutil.DefaultError("Unexpected error while creating gcp storage client.", err),
+				errorsutil.DefaultError(
+					fmt.Sprintf("Unable to read projects file (%s).", projectsFile),
+					err,
+				),
 			)
 			return
 		}
 
-		eg, egCtx := errgroup.WithContext(context.Background())
-		for _, s := range targetSensors {
-			s := s
-			eg.Go(func() error {
-				return s.StreamToBucket(egCtx, client, storageClient, storageBucket)
-			})
+		errorsutil.AddSensitive(falconClientId, falconClientSecret, falconCid, storageBucket, provisioningToken, maintenanceToken)
+		errorsutil.AddSensitive(deployProjects...)
 
-			sensors = append(sensors, &s)
+		ac := falcon.ApiConfig{
+			ClientId:          falconClientId,
+			ClientSecret:      falconClientSecret,
+			Cloud:             cloud,
+			Context:           context.Background(),
+			UserAgentOverride: "crowdstrike-gcp-vm-manager-os-policy/v0.0.2",
+			TransportDecorator: func(rt http.RoundTripper) http.RoundTripper {
+				return logging.NewTransport(rt, logger, "falcon")
+			},
 		}
 
-		storageSyncModel.Sensors = sensors
+		if runDoctor {
+			fmt.Print("Running preflight checks...\n\n")
 
-		p := tea.NewProgram(storageSyncModel)
-		go func() {
-			p.Run()
-		}()
+			ok := doctor.New(doctor.Config{
+				FalconConfig: &ac,
+				Bucket:       storageBucket,
+				Projects:     deployProjects,
+				Zones:        zones,
+				Logger:       logger,
+			}).Run(context.Background(), os.Stdout)
+
+			fmt.Println("")
+
+			if !ok {
+				fmt.Println("One or more preflight checks failed, fix the issues above and try again.")
+				return
+			}
+		}
 
-		err = eg.Wait()
+		targets, err := resolveTargets(context.Background(), deployProjects, logger)
 		if err != nil {
-			p.Quit()
-			p.Wait()
 			fmt.Println(
-				errorsutil.DefaultError(
-					fmt.Sprintf(
-						"An error occurred while downloading and uploading sensor binaries to bucket(%s).",
-						storageBucket,
-					),
-					err,
-				),
+				errorsutil.DefaultError("Unable to determine the GCP compute zones to deploy to.", err),
 			)
 			return
 		}
 
-		p.Wait()
+		client, err := falcon.NewClient(&ac)
 
-		fmt.Print("Download and upload complete...\n\n")
+		if err != nil {
+			fmt.Println(
+				errorsutil.DefaultError("Unexpected error while creating falcon client.", err),
+			)
+			return
+		}
+
+		// autodiscover resolves the cloud when the client is created.
+		cloud = ac.Cloud
+		errorsutil.SetDiagnostic("Falcon cloud", cloud.String())
+
+		if (fetchMaintenanceToken || len(maintenanceTokenAids) > 0) && !uninstall && !enforceVersion {
+			fmt.Printf(
+				"%s Maintenance tokens are only used with --uninstall or --enforce-version, skipping...\n",
+				tui.Yellow(tui.WarningIcon),
+			)
+			fetchMaintenanceToken = false
+			maintenanceTokenAids = nil
+		}
+
+		if fetchMaintenanceToken {
+			fmt.Println("Revealing the bulk maintenance token...")
+
+			token, err := falconutil.MaintenanceToken(client)
+			if errors.Is(err, falconutil.ErrMaintenanceTokenScope) {
+				warnMaintenanceTokenScope(err)
+			} else if err != nil {
+				fmt.Println(
+					errorsutil.DefaultError("Unexpected error while revealing the maintenance token.", err),
+				)
+				return
+			}
+
+			errorsutil.AddSensitive(token)
+			maintenanceToken = token
+		}
+
+		var maintenanceTokens map[string]string
+		if len(maintenanceTokenAids) > 0 {
+			fmt.Printf("Revealing the maintenance tokens of %d host(s)...\n", len(maintenanceTokenAids))
+
+			maintenanceTokens, err = falconutil.MaintenanceTokens(client, maintenanceTokenAids)
+			if errors.Is(err, falconutil.ErrMaintenanceTokenScope) {
+				warnMaintenanceTokenScope(err)
+			} else if err != nil {
+				fmt.Println(
+					errorsutil.DefaultError("Unexpected error while revealing the maintenance tokens.", err),
+				)
+				return
+			}
+
+			for _, token := range maintenanceTokens {
+				errorsutil.AddSensitive(token)
+			}
+		}
+
+		if falconCid == "" && !uninstall {
+			fmt.Println("No cid provided, grabbing cid...")
+
+			cid, err := falconutil.CID(client)
+			if err != nil {
+				fmt.Println(
+					errorsutil.DefaultError("Unexpected error while grabbing cid.", err),
+				)
+				return
+			}
+
+			fmt.Printf("Using cid: %s\n", cid)
+			falconCid = cid
+			errorsutil.AddSensitive(falconCid)
+		}
+
+		var sensors []*sensor.Sensor
+		var signingKeyPath string
+		var signingKeyGeneration int64
+		if !uninstall {
+			storageClient, err := gcputil.NewStorageClient(context.Background(), logger)
+
+			if err != nil {
+				fmt.Println(
+					errorsutil.DefaultError("Unexpected error while creating gcp storage client.", err),
+				)
+				return
+			}
+
+			sensors, err = stageSensors(client, storageClient, ac.Cloud, limiter, logger)
+			if err != nil {
+				fmt.Println(
+					errorsutil.DefaultError(
+						fmt.Sprintf(
+							"An error occurred while downloading and uploading sensor binaries to bucket(%s).",
+							storageBucket,
+						),
+						err,
+					),
+				)
+				return
+			}
+
+			if signingKey != nil {
+				signingKeyPath, signingKeyGeneration, err = sensor.UploadSigningKey(
+					context.Background(),
+					storageClient,
+					storageBucket,
+					signingKey,
+					logger,
+				)
+				if err != nil {
+					fmt.Println(
+						errorsutil.DefaultError(
+							fmt.Sprintf("Unable to upload the signing key to bucket(%s).", storageBucket),
+							err,
+						),
+					)
+					return
+				}
+			}
+
+			fmt.Print("Download and upload complete...\n\n")
+		}
 		fmt.Println("Generating GCP OS Policy template...")
 
 		policy := policy.NewPolicy(
 			falconCid,
 			linuxInstallParams,
 			windowsInstallParams,
+			sensorOptions,
 			sensors,
 			inclusionLabels,
 			exclusionLabels,
 		)
+		policy.Rollout = rollout
+		policy.Mode = policyMode
+		policy.EnforceVersion = enforceVersion
+		policy.Uninstall = uninstall
+		policy.MaintenanceToken = maintenanceToken
+		policy.MaintenanceTokens = maintenanceTokens
+		policy.MaintenanceTokenSecret = maintenanceTokenSecret
+		policy.InstallParams = cfg.InstallParams
+		policy.Hooks = cfg.Hooks
+		policy.Staging = cfg.Staging
+		policy.Template = string(customTemplate)
+		if signingKeyPath != "" {
+			policy.SetSigningKey(signingKeyPath, signingKeyGeneration)
+		}
 
-		policyFilePath := filepath.Join(outputDir, "template.json")
-		policyFile, err := os.Create(policyFilePath)
+		err = policy.Validate()
+		if err != nil {
+			fmt.Println(
+				errorsutil.DefaultError(
+					"The generated GCP OS Policy is invalid. No assignments were created.",
+					err,
+				),
+			)
+			return
+		}
+
+		policyFilePath := outputFile
+		if policyFilePath == "" {
+			policyFilePath = filepath.Join(outputDir, "template."+policyFormat)
+		}
+		if policy.HasPlaintextMaintenanceTokens() {
+			fmt.Printf(
+				"%s Maintenance tokens are written into the OS Policy in plaintext. Anyone who can view OS Policy Assignments in the project can read them. Use --maintenance-token-secret to read the bulk token from Secret Manager instead.\n\n",
+				tui.Yellow(tui.WarningIcon),
+			)
+		}
+
+		// the template can contain maintenance and provisioning tokens.
+		policyFile, err := os.OpenFile(policyFilePath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+		if err == nil {
+			err = policyFile.Chmod(0o600)
+		}
 
 		if err != nil {
 			fmt.Println(
@@ -394,7 +547,7 @@ var createCmd = &cobra.Command{
 			return
 		}
 
-		err = policy.GeneratePolicy(policyFile)
+		err = policy.GeneratePolicy(policyFile, policyFormat)
 		if err != nil {
 			fmt.Println(
 				errorsutil.DefaultError(fmt.Sprintf(
@@ -409,7 +562,11 @@ var createCmd = &cobra.Command{
 
 		fmt.Printf("GCP OS Policy template successfully generated (%s)\n\n", policyFilePath)
 
-		err = processZones(policyFilePath)
+		if rolloutWaves > 0 {
+			err = processWaves(targets, policyFilePath, rollout, assignmentPrefix, policy.InstanceFilter(), logger)
+		} else {
+			err = processZones(targets, policyFilePath, rollout, assignmentPrefix, logger)
+		}
 
 		if err != nil {
 			fmt.Println(
@@ -422,6 +579,10 @@ var createCmd = &cobra.Command{
 		}
 
 		fmt.Println("Policy Assignments created successfully.")
+
+		if validationMode {
+			fmt.Println("Run `cs-policy status --mode=validation` with the same zone flags to see which VMs are missing the sensor.")
+		}
 	},
 }
 
@@ -429,29 +590,285 @@ func NewCreateCmd() *cobra.Command {
 	return createCmd
 }
 
-// processZones handles the logic to create os policy assignments in each gcp compute zone
-func processZones(policyFilePath string) error {
+// warnMaintenanceTokenScope warns that protected sensors can not be removed or upgraded
+// without maintenance tokens.
+func warnMaintenanceTokenScope(err error) {
+	fmt.Printf(
+		"%s Unable to reveal maintenance tokens: %s.\n  Sensors with uninstall protection will not be removed or upgraded. Add the scope to the API client in the Falcon console or use --maintenance-token.\n",
+		tui.Yellow(tui.WarningIcon),
+		err,
+	)
+}
+
+// stageSensors downloads the sensor of every supported os from the Falcon API and uploads it to the storage bucket.
+func stageSensors(
+	client *client.CrowdStrikeAPISpecification,
+	storageClient *storage.Client,
+	cloud falcon.CloudType,
+	limiter *throttle.Limiter,
+	logger *slog.Logger,
+) ([]*sensor.Sensor, error) {
+	targetSensors := []sensor.Sensor{
+		{
+			Filter:       "os:'*RHEL*'+os_version:'7'+platform:'linux'",
+			OsShortName:  "rhel",
+			OsVersion:    "7*",
+			Platform:     "linux",
+			Cloud:        cloud,
+			BucketPrefix: fmt.Sprintf("crowdstrike/falcon/%s/linux/rhel/7", cloud.String()),
+		},
+		{
+			Filter:       "os:'*RHEL*'+os_version:'8'+platform:'linux'",
+			OsShortName:  "rhel",
+			OsVersion:    "8*",
+			Platform:     "linux",
+			Cloud:        cloud,
+			BucketPrefix: fmt.Sprintf("crowdstrike/falcon/%s/linux/rhel/8", cloud.String()),
+		},
+		{
+			Filter:       "os:'*RHEL*'+os_version:'9'+platform:'linux'",
+			OsShortName:  "rhel",
+			OsVersion:    "9*",
+			Platform:     "linux",
+			Cloud:        cloud,
+			BucketPrefix: fmt.Sprintf("crowdstrike/falcon/%s/linux/rhel/9", cloud.String()),
+		},
+		{
+			Filter:       "os:'*RHEL*'+os_version:'10'+platform:'linux'",
+			OsShortName:  "rhel",
+			OsVersion:    "10*",
+			Platform:     "linux",
+			Cloud:        cloud,
+			BucketPrefix: fmt.Sprintf("crowdstrike/falcon/%s/linux/rhel/10", cloud.String()),
+		},
+		{
+			Filter:      "os:'*CentOS*'+os_version:'8'+platform:'linux'",
+			OsShortName: "centos",
+			OsVersion:   "8*",
+			Platform:    "linux",
+			Cloud:       cloud,
+			BucketPrefix: fmt.Sprintf(
+				"crowdstrike/falcon/%s/linux/centos/8",
+				cloud.String(),
+			),
+		},
+		{
+			Filter:      "os:'*CentOS Stream*'+os_version:'9'+platform:'linux'",
+			OsShortName: "centos",
+			OsVersion:   "9*",
+			Platform:    "linux",
+			Cloud:       cloud,
+			BucketPrefix: fmt.Sprintf(
+				"crowdstrike/falcon/%s/linux/centos/9",
+				cloud.String(),
+			),
+		},
+		{
+			Filter:      "os:'*CentOS Stream*'+os_version:'10'+platform:'linux'",
+			OsShortName: "centos",
+			OsVersion:   "10*",
+			Platform:    "linux",
+			Cloud:       cloud,
+			BucketPrefix: fmt.Sprintf(
+				"crowdstrike/falcon/%s/linux/centos/10",
+				cloud.String(),
+			),
+		},
+		{
+			Filter:      "os:'*SLES*'+os_version:'12'+platform:'linux'",
+			OsShortName: "sles",
+			OsVersion:   "12*",
+			Platform:    "linux",
+			Cloud:       cloud,
+			BucketPrefix: fmt.Sprintf(
+				"crowdstrike/falcon/%s/linux/sles/12",
+				cloud.String(),
+			),
+		},
+		{
+			Filter:      "os:'*SLES*'+os_version:'15'+platform:'linux'",
+			OsShortName: "sles",
+			OsVersion:   "15*",
+			Platform:    "linux",
+			Cloud:       cloud,
+			BucketPrefix: fmt.Sprintf(
+				"crowdstrike/falcon/%s/linux/sles/15",
+				cloud.String(),
+			),
+		},
+		{
+			Filter:      "os:'*Ubuntu*'+os_version:'*16/18/20/22/24*'+os_version:!'*arm64*'+os_version:!~'zLinux'+platform:'linux'",
+			OsShortName: "ubuntu",
+			Platform:    "linux",
+			Cloud:       cloud,
+			BucketPrefix: fmt.Sprintf(
+				"crowdstrike/falcon/%s/linux/ubuntu",
+				cloud.String(),
+			),
+		},
+		{
+			Filter:      "os:'Debian'+os_version:'*9/10/11/12/13*'+os_version:!'*arm64*'+platform:'linux'",
+			OsShortName: "debian",
+			Platform:    "linux",
+			Cloud:       cloud,
+			BucketPrefix: fmt.Sprintf(
+				"crowdstrike/falcon/%s/linux/debian",
+				cloud.String(),
+			),
+		},
+		{
+			Filter:      "os:'Windows'+platform:'windows'",
+			OsShortName: "windows",
+			Platform:    "windows",
+			Cloud:       cloud,
+			BucketPrefix: fmt.Sprintf(
+				"crowdstrike/falcon/%s/windows",
+				cloud.String(),
+			),
+		},
+		{
+			Filter:      "os:'*Oracle*'+os_version:'7'+platform:'linux'",
+			OsShortName: "ol",
+			OsVersion:   "7*",
+			Platform:    "linux",
+			Cloud:       cloud,
+			BucketPrefix: fmt.Sprintf(
+				"crowdstrike/falcon/%s/linux/oracle/7",
+				cloud.String(),
+			),
+		},
+		{
+			Filter:      "os:'*Oracle*'+os_version:'8'+platform:'linux'",
+			OsShortName: "ol",
+			OsVersion:   "8*",
+			Platform:    "linux",
+			Cloud:       cloud,
+			BucketPrefix: fmt.Sprintf(
+				"crowdstrike/falcon/%s/linux/oracle/8",
+				cloud.String(),
+			),
+		},
+		{
+			Filter:      "os:'*Oracle*'+os_version:'9'+platform:'linux'",
+			OsShortName: "ol",
+			OsVersion:   "9*",
+			Platform:    "linux",
+			Cloud:       cloud,
+			BucketPrefix: fmt.Sprintf(
+				"crowdstrike/falcon/%s/linux/oracle/9",
+				cloud.String(),
+			),
+		},
+		{
+			Filter:      "os:'*Oracle*'+os_version:'10'+platform:'linux'",
+			OsShortName: "ol",
+			OsVersion:   "10*",
+			Platform:    "linux",
+			Cloud:       cloud,
+			BucketPrefix: fmt.Sprintf(
+				"crowdstrike/falcon/%s/linux/oracle/10",
+				cloud.String(),
+			),
+		},
+	}
+
+	var storageSyncModel tui.StorageSyncModel
+	var sensors []*sensor.Sensor
+
+	for _, s := range targetSensors {
+		s := s
+		s.CacheDir = cacheDir
+		s.ChunkSize = chunkSize << 20
+		s.Limiter = limiter
+		s.Logger = logger
+		sensors = append(sensors, &s)
+	}
+
+	storageSyncModel.Sensors = sensors
+
+	p := tea.NewProgram(storageSyncModel)
+	go func() {
+		p.Run()
+	}()
+
+	// the sensors waiting for a slot are shown without progress until they start.
+	eg, egCtx := errgroup.WithContext(context.Background())
+	eg.SetLimit(concurrency)
+	for _, s := range sensors {
+		eg.Go(func() error {
+			return s.StreamToBucket(egCtx, client, storageClient, storageBucket)
+		})
+	}
+
+	err := eg.Wait()
+	if err != nil {
+		p.Quit()
+		p.Wait()
+		return nil, err
+	}
+
+	p.Wait()
+
+	for _, s := range sensors {
+		logger.Info(
+			"sensor staged",
+			"os", s.OsShortName+s.OsVersion,
+			"version", *s.SensorInfo.Version,
+			"object", s.FullPath,
+			"generation", s.Generation,
+		)
+	}
+
+	return sensors, nil
+}
+
+// processZones handles the logic to create os policy assignments in each gcp compute zone of each project
+//
+// Projects are rolled out concurrently and a failure in one project does not cancel the others.
+func processZones(
+	targets map[string][]string,
+	policyFilePath string,
+	rollout policy.Rollout,
+	namePrefix string,
+	logger *slog.Logger,
+) error {
 	policyModel := tui.NewPolicyModel()
+	policyModel.Rollout = rollout
 	var assignments []*policy.Assignment
 
 	// sort alphabetically
-	sort.Slice(zones, func(i, j int) bool {
-		return zones[i] < zones[j]
-	})
+	targetProjects := gcputil.SortedProjects(targets)
 
-	eg, egCtx := errgroup.WithContext(context.Background())
-	for _, z := range zones {
-		z := z
-		a := policy.Assignment{
-			Zone:               z,
-			PolicyTemplatePath: policyFilePath,
-			SkipWait:           skipWait,
+	var wg sync.WaitGroup
+	projectErrs := make([]error, len(targetProjects))
+
+	for i, project := range targetProjects {
+		i, project := i, project
+		eg, egCtx := errgroup.WithContext(context.Background())
+		for _, z := range targets[project] {
+			z := z
+			a := policy.Assignment{
+				Project:            project,
+				Zone:               z,
+				NamePrefix:         namePrefix,
+				PolicyTemplatePath: policyFilePath,
+				SkipWait:           skipWait,
+				Logger:             logger,
+			}
+			eg.Go(func() error {
+				return a.RollOut(egCtx)
+			})
+
+			assignments = append(assignments, &a)
 		}
-		eg.Go(func() error {
-			return a.RollOut(egCtx)
-		})
 
-		assignments = append(assignments, &a)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := eg.Wait(); err != nil {
+				projectErrs[i] = fmt.Errorf("%s: %w", tui.ProjectName(project), err)
+			}
+		}()
 	}
 
 	policyModel.Assignments = assignments
@@ -463,7 +880,9 @@ func processZones(policyFilePath string) error {
 		p.Run()
 	}()
 
-	err := eg.Wait()
+	wg.Wait()
+
+	err := errors.Join(projectErrs...)
 	if err != nil {
 		p.Quit()
 		p.Wait()
@@ -474,6 +893,277 @@ func processZones(policyFilePath string) error {
 	return nil
 }
 
+// processWaves creates the os policy assignments wave by wave
+//
+// Each wave waits for its assignments to roll out and for the os config compliance reports of
+// its VMs to reach the compliance threshold before the next wave starts. The rollout stops if a
+// wave's non-compliant VMs exceed the failure limit or the wave times out.
+func processWaves(
+	targets map[string][]string,
+	policyFilePath string,
+	rollout policy.Rollout,
+	namePrefix string,
+	filter policy.InstanceFilter,
+	logger *slog.Logger,
+) error {
+	ctx := context.Background()
+
+	gcpClient, err := gcputil.NewClient(ctx, logger)
+	if err != nil {
+		return err
+	}
+
+	targetProjects := gcputil.SortedProjects(targets)
+
+	var assignments []*policy.Assignment
+	for _, project := range targetProjects {
+		for _, z := range targets[project] {
+			assignments = append(assignments, &policy.Assignment{
+				Project:            project,
+				Zone:               z,
+				NamePrefix:         namePrefix,
+				PolicyTemplatePath: policyFilePath,
+				Logger:             logger,
+			})
+		}
+	}
+
+	waves, err := policy.PlanWaves(assignments, rolloutWaves, canaryZones)
+	if err != nil {
+		return err
+	}
+
+	policyModel := tui.NewPolicyModel()
+	policyModel.Rollout = rollout
+	policyModel.Assignments = assignments
+	policyModel.Waves = waves
+	p := tea.NewProgram(policyModel)
+
+	fmt.Printf("Creating GCP OS Policy Assignments in %d waves...\n", len(waves))
+
+	go func() {
+		p.Run()
+	}()
+
+	for _, w := range waves {
+		if err := processWave(ctx, gcpClient, w, filter, logger); err != nil {
+			w.Fail(err.Error())
+			p.Quit()
+			p.Wait()
+			return fmt.Errorf("rollout stopped at wave %d of %d: %w", w.Number, len(waves), err)
+		}
+	}
+
+	p.Wait()
+
+	return nil
+}
+
+// processWave rolls out a single wave and waits for its VMs to become compliant.
+func processWave(
+	ctx context.Context,
+	gcpClient *gcputil.Client,
+	w *policy.Wave,
+	filter policy.InstanceFilter,
+	logger *slog.Logger,
+) error {
+	logger = logger.With("wave", w.Number)
+	w.SetStatus(policy.WaveRollingOut)
+
+	eg, egCtx := errgroup.WithContext(ctx)
+	for _, a := range w.Assignments {
+		a := a
+		eg.Go(func() error {
+			return a.RollOut(egCtx)
+		})
+	}
+
+	if err := eg.Wait(); err != nil {
+		return err
+	}
+
+	w.SetStatus(policy.WaveVerifying)
+
+	// a wave without VMs never gets compliance reports, so it does not wait for them.
+	vms, err := countInstances(ctx, gcpClient, w.Assignments, filter)
+	if err != nil {
+		return err
+	}
+	if vms == 0 {
+		logger.Info("wave has no running VMs, skipping compliance check")
+		w.SetStatus(policy.WaveSucceeded)
+		return nil
+	}
+
+	timeout := time.After(waveTimeout)
+	for {
+		var compliance gcputil.Compliance
+		for _, a := range w.Assignments {
+			reports, err := gcpClient.AssignmentReports(ctx, a.Project, a.Zone, a.Name())
+			if err != nil {
+				return fmt.Errorf("unable to get compliance reports for %s: %w", a.Zone, err)
+			}
+			compliance = compliance.Add(gcputil.Summarize(reports))
+		}
+
+		// VMs that have not reported yet count as not compliant.
+		total := max(vms, compliance.Total())
+		w.SetCompliance(compliance.Compliant, compliance.NonCompliant, total)
+		logger.Info(
+			"wave compliance",
+			"compliant", compliance.Compliant,
+			"non_compliant", compliance.NonCompliant,
+			"unknown", compliance.Unknown,
+			"vms", vms,
+		)
+
+		done, err := policy.EvaluateWave(
+			compliance.Compliant,
+			compliance.NonCompliant,
+			total,
+			waveComplianceThreshold,
+			waveFailureLimit,
+		)
+		if err != nil {
+			return err
+		}
+
+		if done {
+			w.SetStatus(policy.WaveSucceeded)
+			return nil
+		}
+
+		select {
+		case <-timeout:
+			return fmt.Errorf(
+				"timed out after %s waiting for %.0f%% of VMs to become compliant (%d/%d compliant)",
+				waveTimeout,
+				waveComplianceThreshold,
+				compliance.Compliant,
+				total,
+			)
+		case <-time.After(wavePollInterval):
+		}
+	}
+}
+
+// countInstances counts the running VMs in the zones of the assignments that the policy covers,
+// i.e. the VMs selected by the filter's labels that do not run Container-Optimized OS.
+func countInstances(
+	ctx context.Context,
+	gcpClient *gcputil.Client,
+	assignments []*policy.Assignment,
+	filter policy.InstanceFilter,
+) (int, error) {
+	var count int
+	for _, a := range assignments {
+		instances, err := gcpClient.Instances(ctx, a.Project, a.Zone)
+		if err != nil {
+			return 0, fmt.Errorf("unable to list the VMs in %s: %w", a.Zone, err)
+		}
+		count += len(verify.Filter(instances, filter))
+	}
+	return count, nil
+}
+
+// validateWaveFlags checks the wave flags and enables two waves when only canary zones are provided.
+func validateWaveFlags() error {
+	if rolloutWaves < 0 {
+		return fmt.Errorf("invalid --rollout-waves %d: must not be negative", rolloutWaves)
+	}
+
+	if len(canaryZones) > 0 && rolloutWaves == 0 {
+		rolloutWaves = 2
+	}
+
+	if waveComplianceThreshold <= 0 || waveComplianceThreshold > 100 {
+		return fmt.Errorf(
+			"invalid --wave-compliance-threshold %.0f: must be greater than 0 and at most 100",
+			waveComplianceThreshold,
+		)
+	}
+
+	if waveFailureLimit < 0 || waveFailureLimit > 100 {
+		return fmt.Errorf("invalid --wave-failure-limit %.0f: must be between 0 and 100", waveFailureLimit)
+	}
+
+	if waveTimeout <= 0 {
+		return fmt.Errorf("invalid --wave-timeout %s: must be greater than 0", waveTimeout)
+	}
+
+	return nil
+}
+
+// validateTransferFlags checks the sensor transfer flags and returns the limiter shared by every
+// sensor's download and upload.
+func validateTransferFlags() (*throttle.Limiter, error) {
+	if concurrency < 1 {
+		return nil, fmt.Errorf("invalid --concurrency %d: must be at least 1", concurrency)
+	}
+
+	if chunkSize < 1 {
+		return nil, fmt.Errorf("invalid --chunk-size %d: must be at least 1 MiB", chunkSize)
+	}
+
+	bytesPerSecond, err := throttle.ParseBandwidth(maxBandwidth)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --max-bandwidth: %w", err)
+	}
+
+	if cacheDir == "" {
+		dir, err := os.UserCacheDir()
+		if err != nil {
+			dir = os.TempDir()
+		}
+		cacheDir = filepath.Join(dir, "cs-policy", "sensors")
+	}
+
+	return throttle.NewLimiter(bytesPerSecond), nil
+}
+
+// resolveTargets validates and expands the zone flags against the compute api for each project.
+//
+// Projects without any matching zones are skipped.
+func resolveTargets(ctx context.Context, deployProjects []string, logger *slog.Logger) (map[string][]string, error) {
+	gcpClient, err := gcputil.NewClient(ctx, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	resolved, err := gcpClient.ResolveTargets(ctx, deployProjects, gcputil.ZoneSelector{
+		Zones:         zones,
+		Regions:       regions,
+		AllZones:      allZones,
+		WithInstances: zonesWithInstances,
+	})
+	if len(resolved) == 0 && err != nil {
+		return nil, err
+	}
+	tui.WarnFailedProjects(err)
+
+	targets := map[string][]string{}
+	for _, project := range gcputil.SortedProjects(resolved) {
+		errorsutil.AddSensitive(project)
+		projectZones := resolved[project]
+
+		if len(projectZones) == 0 {
+			fmt.Printf("%s No matching zones found in project %s, skipping...\n", tui.Yellow(tui.WarningIcon), project)
+			continue
+		}
+
+		logger.Info("resolved target zones", "project", project, "zones", projectZones)
+		fmt.Printf("Targeting %d zone(s) in project %s: %s\n", len(projectZones), project, strings.Join(projectZones, ", "))
+		targets[project] = projectZones
+	}
+
+	if len(targets) == 0 {
+		return nil, errors.New("no zones matched the provided zone flags in any project")
+	}
+
+	fmt.Println("")
+	return targets, nil
+}
+
 func init() {
 	dir, _ := os.Getwd()
 	createCmd.PersistentFlags().
@@ -485,23 +1175,117 @@ func init() {
 	createCmd.Flags().
 		StringVar(&falconCid, "falcon-cid", "", "Falcon CID to use on install. Can also bet set by the FALCON_CID environment variable. Will be pulled from the api if not provided")
 	createCmd.Flags().
-		StringVar(&linuxInstallParams, "linux-install-params", "", "The parameters to pass at install time on Linux machines (excluding CID)")
+		StringVar(&linuxInstallParams, "linux-install-params", "", "The parameters to pass at install time on Linux machines (excluding CID). Overridden per OS by the config file's installParams")
+	createCmd.Flags().
+		StringVar(&windowsInstallParams, "windows-install-params", "", "The parameters to pass at install time on Windows machines (excluding CID). Can also be set by the config file's installParams")
+	createCmd.Flags().
+		StringSliceVar(&sensorTags, "tags", []string{}, "Sensor grouping tags applied on Linux and Windows")
+	createCmd.Flags().
+		StringVar(&proxyHost, "proxy-host", "", "Proxy host the sensor connects through")
+	createCmd.Flags().
+		IntVar(&proxyPort, "proxy-port", 0, "Proxy port the sensor connects through. Requires --proxy-host")
 	createCmd.Flags().
-		StringVar(&windowsInstallParams, "windows-install-params", "", "The parameters to pass at install time on Windows machines (excluding CID)")
+		BoolVar(&disableProxy, "disable-proxy", false, "Disable the sensor's proxy")
+	createCmd.Flags().
+		StringVar(&provisioningToken, "provisioning-token", "", "Sensor provisioning token. Can also bet set by the FALCON_PROVISIONING_TOKEN environment variable")
+	createCmd.Flags().
+		StringVar(&provisioningTokenSecret, "provisioning-token-secret", "", "Secret Manager secret the VMs read the provisioning token from at install time, e.g. projects/my-project/secrets/falcon-token. Keeps the token out of the OS Policy")
+	createCmd.Flags().
+		StringVar(&sensorBackend, "sensor-backend", "", fmt.Sprintf("Linux sensor backend one of %s", strings.Join(policy.SensorBackends, ", ")))
+	createCmd.Flags().
+		StringVar(&billing, "billing", "", fmt.Sprintf("Sensor billing type one of %s", strings.Join(policy.BillingTypes, ", ")))
 	createCmd.Flags().
 		StringVar(&storageBucket, "bucket", "", "GCP cloud storage bucket to upload sensor binaries")
 	createCmd.Flags().
 		StringVar(&outputDir, "output-dir", dir, "GCP OS Policy template output directory")
+	createCmd.Flags().
+		StringVar(&outputFile, "output-file", "", "GCP OS Policy template output file. Overrides --output-dir")
+	createCmd.Flags().
+		StringVar(&outputFormat, "format", policy.FormatJSON, "GCP OS Policy template format one of json, yaml")
+	createCmd.Flags().
+		StringVar(&configFile, "config", "", "Path to a yaml or json config file with per OS hooks and install params")
+	createCmd.Flags().
+		StringVar(&templateFile, "template", "", "Path to a json or yaml GCP OS Policy template that replaces the built-in policy. Rendered as a Go template with the generated policy data")
+	createCmd.Flags().
+		StringVar(&gpgKeyFile, "gpg-key", "", "Path to the ascii armored CrowdStrike signing key. Uploaded to the bucket and used to verify the Linux installers before they are installed. Can also bet set by the FALCON_GPG_KEY environment variable")
+	createCmd.Flags().
+		BoolVar(&skipGPGVerify, "skip-gpg-verify", false, "Install the Linux sensors without verifying their signature, for legacy images that can not verify signatures. SLES installs with zypper --no-gpg-checks")
 	createCmd.Flags().StringSliceVar(&zones, "zones", []string{}, "GCP compute zones to deploy to")
 	createCmd.Flags().
-		BoolVar(&skipWait, "skip-wait", false, "Skip waiting for the rollout of GCP OS Policy Assignments to complete")
+		StringSliceVar(&regions, "regions", []string{}, "GCP compute regions to deploy to. Expanded to every zone in the region")
+	createCmd.Flags().
+		BoolVar(&allZones, "all-zones", false, "Deploy to every GCP compute zone available in the project")
 	createCmd.Flags().
-		BoolVar(&debug, "debug", false, "Enable debug logging")
+		BoolVar(&zonesWithInstances, "zones-with-instances", false, "Only deploy to zones that contain Compute Engine VMs. Limits --zones, --regions and --all-zones when combined")
+	createCmd.Flags().
+		StringVar(&project, "project", "", "GCP project to deploy to. Defaults to the gcloud cli's active project")
+	createCmd.Flags().
+		StringSliceVar(&projects, "projects", []string{}, "GCP projects to deploy to. Assignments are created in each project concurrently")
+	createCmd.Flags().
+		StringVar(&projectsFile, "projects-file", "", "File containing GCP projects to deploy to, one per line")
+	createCmd.Flags().
+		BoolVar(&skipWait, "skip-wait", false, "Skip waiting for the rollout of GCP OS Policy Assignments to complete")
 	// rootCmd.Flags().
 	// 	StringArrayVar(&inclusionLabels, "include-labelset", []string{}, "A comma separated list of labels. In the format of labelName:labelValue. Matches only if a VM has all the labels in the labelset. Example: Label:Value,Env:Prod")
 	// rootCmd.Flags().
 	// 	StringArrayVar(&exclusionLabels, "exclude-labelset", []string{}, "A comma separated list of labels. In the format of labelName:labelValue. Matches only if a VM has none of the labels in the labelset. Example: Label:Value,Env:Prod")
-	createCmd.MarkFlagRequired("zones")
+	createCmd.Flags().
+		StringVar(&rolloutProfile, "rollout-profile", policy.DefaultRolloutProfile, fmt.Sprintf("Rollout pacing defaults one of %s", strings.Join(policy.RolloutProfileNames(), ", ")))
+	createCmd.Flags().
+		StringVar(&disruptionBudget, "disruption-budget", "", "Maximum VMs per zone updated at once, as a percentage (25%) or a fixed number (10). Overrides the rollout profile")
+	createCmd.Flags().
+		StringVar(&minWaitDuration, "min-wait-duration", "", "Minimum time to wait between rollout batches, e.g. 300s or 5m. Overrides the rollout profile")
+	createCmd.Flags().
+		StringVar(&mode, "mode", "enforcement", "OS policy mode one of enforcement, validation. Validation only reports which VMs are missing the sensor")
+	createCmd.Flags().
+		BoolVar(&enforceVersion, "enforce-version", false, "Upgrade VMs running a sensor older than the staged sensor. By default VMs with any sensor installed are left to Sensor Update Policies")
+	createCmd.Flags().
+		BoolVar(&uninstall, "uninstall", false, "Create decommission assignments that stop and remove the sensor instead of installing it")
+	createCmd.Flags().
+		StringVar(&maintenanceToken, "maintenance-token", "", "Maintenance token used to remove sensors with uninstall protection. Can also bet set by the FALCON_MAINTENANCE_TOKEN environment variable")
+	createCmd.Flags().
+		BoolVar(&fetchMaintenanceToken, "fetch-maintenance-token", false, "Reveal the bulk maintenance token with the Falcon API. Requires the Sensor Update Policies (Write) scope")
+	createCmd.Flags().
+		StringVar(&maintenanceTokenSecret, "maintenance-token-secret", "", "Secret Manager secret the VMs read the bulk maintenance token from, e.g. projects/my-project/secrets/falcon-maintenance-token. Keeps the token out of the OS Policy")
+	createCmd.Flags().
+		StringSliceVar(&maintenanceTokenAids, "maintenance-token-aids", []string{}, "Reveal the maintenance tokens of these host AIDs with the Falcon API. Other hosts use the bulk maintenance token")
+	createCmd.Flags().
+		IntVar(&rolloutWaves, "rollout-waves", 0, "Roll out to the zones in this many waves, waiting for each wave's VMs to become compliant before starting the next")
+	createCmd.Flags().
+		StringSliceVar(&canaryZones, "canary-zones", []string{}, "Zones that make up the first rollout wave. Enables --rollout-waves=2 when not set")
+	createCmd.Flags().
+		Float64Var(&waveComplianceThreshold, "wave-compliance-threshold", 90, "Percentage of a wave's VMs that must be compliant before the next wave starts")
+	createCmd.Flags().
+		Float64Var(&waveFailureLimit, "wave-failure-limit", 10, "Stop the rollout when more than this percentage of a wave's VMs are non-compliant")
+	createCmd.Flags().
+		DurationVar(&waveTimeout, "wave-timeout", time.Hour, "Maximum time to wait for a wave's VMs to reach the compliance threshold")
+	createCmd.Flags().
+		IntVar(&concurrency, "concurrency", 4, "Maximum sensors downloaded and uploaded to the bucket at once")
+	createCmd.Flags().
+		StringVar(&maxBandwidth, "max-bandwidth", "", "Maximum combined download and upload bandwidth of the sensors in bytes per second, e.g. 512K or 10M. Unlimited by default")
+	createCmd.Flags().
+		IntVar(&chunkSize, "chunk-size", 16, "Size in MiB of each resumable upload request to the bucket. A failed chunk is retried without restarting the upload")
+	createCmd.Flags().
+		StringVar(&cacheDir, "cache-dir", "", "Directory the sensors are downloaded to before they are uploaded. Defaults to the user cache directory")
+	createCmd.Flags().
+		BoolVar(&runDoctor, "doctor", false, "Run the doctor preflight checks before creating the assignments")
+	createCmd.MarkFlagsOneRequired("zones", "regions", "all-zones", "zones-with-instances")
+	createCmd.MarkFlagsMutuallyExclusive("all-zones", "zones")
+	createCmd.MarkFlagsMutuallyExclusive("all-zones", "regions")
+	createCmd.MarkFlagsMutuallyExclusive("skip-wait", "rollout-waves")
+	createCmd.MarkFlagsMutuallyExclusive("skip-wait", "canary-zones")
+	createCmd.MarkFlagsMutuallyExclusive("output-dir", "output-file")
+	createCmd.MarkFlagsMutuallyExclusive("provisioning-token", "provisioning-token-secret")
+	createCmd.MarkFlagsMutuallyExclusive("disable-proxy", "proxy-host")
+	createCmd.MarkFlagsMutuallyExclusive("disable-proxy", "proxy-port")
+	createCmd.MarkFlagsMutuallyExclusive("maintenance-token", "fetch-maintenance-token")
+	createCmd.MarkFlagsMutuallyExclusive("maintenance-token-secret", "maintenance-token")
+	createCmd.MarkFlagsMutuallyExclusive("maintenance-token-secret", "fetch-maintenance-token")
+	createCmd.MarkFlagsMutuallyExclusive("uninstall", "enforce-version")
+	createCmd.MarkFlagsMutuallyExclusive("uninstall", "template")
+	createCmd.MarkFlagsMutuallyExclusive("uninstall", "bucket")
+	createCmd.MarkFlagsMutuallyExclusive("uninstall", "gpg-key")
+	createCmd.MarkFlagsMutuallyExclusive("gpg-key", "skip-gpg-verify")
 
 	if falconClientId == "" {
 		falconClientId = os.Getenv("FALCON_CLIENT_ID")
@@ -518,4 +1302,16 @@ func init() {
 	if falconCid == "" {
 		falconCid = os.Getenv("FALCON_CID")
 	}
+
+	if provisioningToken == "" {
+		provisioningToken = os.Getenv("FALCON_PROVISIONING_TOKEN")
+	}
+
+	if maintenanceToken == "" {
+		maintenanceToken = os.Getenv("FALCON_MAINTENANCE_TOKEN")
+	}
+
+	if gpgKeyFile == "" {
+		gpgKeyFile = os.Getenv("FALCON_GPG_KEY")
+	}
 }
@@ -0,0 +1,127 @@
+package doctor
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+
+	"github.com/MakeNowJust/heredoc"
+	"github.com/crowdstrike/gcp-os-policy/internal/doctor"
+	"github.com/crowdstrike/gcp-os-policy/internal/errorsutil"
+	"github.com/crowdstrike/gcp-os-policy/internal/logging"
+	"github.com/crowdstrike/gofalcon/falcon"
+	"github.com/spf13/cobra"
+)
+
+var falconClientId string
+var falconClientSecret string
+var falconCloud string
+var storageBucket string
+var zones []string
+var projects []string
+
+// doctorCmd represents the cs-policy doctor command
+var doctorCmd = &cobra.Command{
+	Use:   "doctor [flags]",
+	Short: "Check the prerequisites for deploying the Falcon Sensor with GCP OS Policies",
+	Long: `Check the prerequisites for deploying the Falcon Sensor with GCP OS Policies
+
+  The following is checked:
+    - Falcon API credentials, Sensor Download scope and CID lookup
+    - gcloud cli installation and application default credentials
+    - OS Config API and VM Manager are enabled in the project
+    - Permissions to create OS Policy Assignments
+    - The targeted zones exist
+    - The storage bucket exists and can be written to`,
+	Example: heredoc.Doc(`
+    Check the environment before creating assignments in us-central1-a
+    $ cs-policy doctor --bucket=my-bucket --zones=us-central1-a
+    `),
+	Args:          cobra.ExactArgs(0),
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	RunE: func(_ *cobra.Command, _ []string) error {
+		logger := slog.Default()
+
+		if falconCloud == "" {
+			falconCloud = "autodiscover"
+		}
+
+		cloud, err := falcon.CloudValidate(falconCloud)
+		if err != nil {
+			fmt.Println(
+				errorsutil.DefaultError(
+					fmt.Sprintf("Unable to validate %s as falcon cloud.", falconCloud),
+					err,
+				),
+			)
+			return err
+		}
+
+		errorsutil.AddSensitive(falconClientId, falconClientSecret, storageBucket)
+		errorsutil.AddSensitive(projects...)
+
+		ac := falcon.ApiConfig{
+			ClientId:          falconClientId,
+			ClientSecret:      falconClientSecret,
+			Cloud:             cloud,
+			Context:           context.Background(),
+			UserAgentOverride: "crowdstrike-gcp-vm-manager-os-policy/v0.0.2",
+			TransportDecorator: func(rt http.RoundTripper) http.RoundTripper {
+				return logging.NewTransport(rt, logger, "falcon")
+			},
+		}
+
+		fmt.Print("\nRunning preflight checks...\n\n")
+
+		ok := doctor.New(doctor.Config{
+			FalconConfig: &ac,
+			Bucket:       storageBucket,
+			Projects:     projects,
+			Zones:        zones,
+			Logger:       logger,
+		}).Run(context.Background(), os.Stdout)
+
+		fmt.Println("")
+
+		if !ok {
+			fmt.Println("One or more preflight checks failed.")
+			return fmt.Errorf("one or more preflight checks failed")
+		}
+
+		fmt.Println("All preflight checks passed.")
+		return nil
+	},
+}
+
+func NewDoctorCmd() *cobra.Command {
+	return doctorCmd
+}
+
+func init() {
+	doctorCmd.Flags().
+		StringVar(&falconClientId, "falcon-client-id", "", "Falcon API Client Id. Can also bet set by the FALCON_CLIENT_ID environment variable")
+	doctorCmd.Flags().
+		StringVar(&falconClientSecret, "falcon-client-secret", "", "Falcon API Client Secret. Can also bet set by the FALCON_CLIENT_SECRET environment variable")
+	doctorCmd.Flags().
+		StringVar(&falconCloud, "falcon-cloud", "", "Falcon Cloud one of autodiscover, us-1, us-2, eu-1, us-gov-1. Can also bet set by the FALCON_CLOUD environment variable")
+	doctorCmd.Flags().
+		StringVar(&storageBucket, "bucket", "", "GCP cloud storage bucket to check for write access")
+	doctorCmd.Flags().StringSliceVar(&zones, "zones", []string{}, "GCP compute zones to validate")
+	doctorCmd.Flags().
+		StringSliceVar(&projects, "projects", []string{}, "GCP projects to check. Defaults to the gcloud cli's active project")
+
+	if falconClientId == "" {
+		falconClientId = os.Getenv("FALCON_CLIENT_ID")
+	}
+
+	if falconClientSecret == "" {
+		falconClientSecret = os.Getenv("FALCON_CLIENT_SECRET")
+	}
+
+	if falconCloud == "" {
+		falconCloud = os.Getenv("FALCON_CLOUD")
+	}
+}
@@ -0,0 +1,396 @@
+package gke
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/MakeNowJust/heredoc"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/crowdstrike/gcp-os-policy/internal/errorsutil"
+	"github.com/crowdstrike/gcp-os-policy/internal/falconutil"
+	"github.com/crowdstrike/gcp-os-policy/internal/gcputil"
+	"github.com/crowdstrike/gcp-os-policy/internal/kube"
+	"github.com/crowdstrike/gcp-os-policy/internal/logging"
+	"github.com/crowdstrike/gcp-os-policy/internal/policy"
+	"github.com/crowdstrike/gcp-os-policy/internal/sensor"
+	"github.com/crowdstrike/gcp-os-policy/internal/tui"
+	"github.com/crowdstrike/gofalcon/falcon"
+	falconclient "github.com/crowdstrike/gofalcon/falcon/client"
+	"github.com/spf13/cobra"
+)
+
+var falconClientId string
+var falconClientSecret string
+var falconCloud string
+var falconCid string
+var image string
+var artifactRegistry string
+var outputFormat string
+var outputFile string
+var zones []string
+var regions []string
+var allZones bool
+var zonesWithInstances bool
+var projects []string
+var projectsFile string
+var sensorTags []string
+var proxyHost string
+var proxyPort int
+var disableProxy bool
+var provisioningToken string
+var billing string
+
+// gkeCmd represents the cs-policy gke command
+var gkeCmd = &cobra.Command{
+	Use:   "gke [flags]",
+	Short: "Generate the Falcon node sensor manifest for Container-Optimized OS and GKE nodes",
+	Long: `Generate the Falcon node sensor manifest for Container-Optimized OS and GKE nodes
+
+  Container-Optimized OS can not install packages, so the OS Policy does not cover GKE nodes.
+  The node sensor runs on them as a DaemonSet instead. A DaemonSet manifest or a values file
+  for the CrowdStrike falcon-sensor Helm chart is written with the same CID and sensor options
+  as the create command.
+
+  With --artifact-registry the node sensor image is copied from the CrowdStrike registry
+  into an Artifact Registry repository, using the same n-1 version as the installers the
+  create command stages, and the manifest pins the image by digest.
+
+  When zones are given, the OS Config inventory of the VMs is checked and the
+  Container-Optimized OS VMs the OS Policy can not cover are listed.`,
+	Example: heredoc.Doc(`
+    Write a DaemonSet manifest and list the COS VMs in us-central1
+    $ cs-policy gke --image=us-docker.pkg.dev/my-project/falcon/falcon-sensor:7.20.0-17306-1.falcon-linux.Release.US-1 --regions=us-central1
+
+    Write Helm values for the CrowdStrike falcon-sensor chart
+    $ cs-policy gke --image=us-docker.pkg.dev/my-project/falcon/falcon-sensor:7.20.0-17306-1.falcon-linux.Release.US-1 --format=helm
+
+    Stage the node sensor image into Artifact Registry and write a DaemonSet manifest that uses it
+    $ cs-policy gke --artifact-registry=us-docker.pkg.dev/my-project/falcon
+    `),
+	Args:          cobra.ExactArgs(0),
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	RunE: func(_ *cobra.Command, _ []string) error {
+		logger := slog.Default()
+		ctx := context.Background()
+
+		format, err := kube.ParseFormat(outputFormat)
+		if err != nil {
+			fmt.Println(err)
+			return err
+		}
+
+		errorsutil.AddSensitive(falconClientId, falconClientSecret, falconCid, provisioningToken)
+
+		var client *falconclient.CrowdStrikeAPISpecification
+		var cloud falcon.CloudType
+		if falconCid == "" || artifactRegistry != "" {
+			if falconCloud == "" {
+				falconCloud = "autodiscover"
+			}
+
+			cloud, err = falcon.CloudValidate(falconCloud)
+			if err != nil {
+				fmt.Println(
+					errorsutil.DefaultError(
+						fmt.Sprintf("Unable to validate %s as falcon cloud.", falconCloud),
+						err,
+					),
+				)
+				return err
+			}
+
+			ac := &falcon.ApiConfig{
+				ClientId:          falconClientId,
+				ClientSecret:      falconClientSecret,
+				Cloud:             cloud,
+				Context:           ctx,
+				UserAgentOverride: "crowdstrike-gcp-vm-manager-os-policy/v0.0.2",
+				TransportDecorator: func(rt http.RoundTripper) http.RoundTripper {
+					return logging.NewTransport(rt, logger, "falcon")
+				},
+			}
+			client, err = falcon.NewClient(ac)
+			if err != nil {
+				fmt.Println(errorsutil.DefaultError("Unexpected error while creating falcon client.", err))
+				return err
+			}
+			// autodiscover resolves the cloud when the client is created.
+			cloud = ac.Cloud
+		}
+
+		if falconCid == "" {
+			fmt.Println("No cid provided, grabbing cid...")
+			falconCid, err = falconutil.CID(client)
+			if err != nil {
+				fmt.Println(errorsutil.DefaultError("Unexpected error while grabbing cid.", err))
+				return err
+			}
+			errorsutil.AddSensitive(falconCid)
+		}
+
+		if artifactRegistry != "" {
+			img, err := stageImage(ctx, logger, client, cloud)
+			if err != nil {
+				return err
+			}
+			image = img.Reference()
+		}
+
+		sensor := kube.Sensor{
+			Cid:   falconCid,
+			Image: image,
+			Options: policy.SensorOptions{
+				Tags:              sensorTags,
+				ProxyHost:         proxyHost,
+				ProxyPort:         proxyPort,
+				DisableProxy:      disableProxy,
+				ProvisioningToken: provisioningToken,
+				Billing:           billing,
+			},
+		}
+
+		if err := sensor.Validate(); err != nil {
+			fmt.Println(err)
+			return err
+		}
+
+		if len(zones) > 0 || len(regions) > 0 || allZones || zonesWithInstances {
+			if err := detectCOS(ctx, logger); err != nil {
+				return err
+			}
+		}
+
+		path := outputFile
+		if path == "" {
+			path = "falcon-node-sensor.yaml"
+			if format == kube.FormatHelm {
+				path = "falcon-sensor-values.yaml"
+			}
+		}
+
+		// the manifest contains the CID and provisioning token, so it is only readable by the owner.
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+		if err == nil {
+			err = f.Chmod(0o600)
+		}
+		if err != nil {
+			fmt.Println(errorsutil.DefaultError(fmt.Sprintf("Unable to create %s.", path), err))
+			return err
+		}
+		defer f.Close()
+
+		if err := sensor.Write(f, format); err != nil {
+			fmt.Println(errorsutil.DefaultError(fmt.Sprintf("Unable to write %s.", path), err))
+			return err
+		}
+
+		if format == kube.FormatHelm {
+			fmt.Printf("%s Wrote the Falcon node sensor Helm values to %s\n", tui.Green(tui.SuccessIcon), path)
+			fmt.Printf(
+				"  Deploy it to each cluster with: helm upgrade --install falcon-sensor crowdstrike/falcon-sensor -n %s --create-namespace -f %s\n",
+				kube.Namespace,
+				filepath.Base(path),
+			)
+		} else {
+			fmt.Printf("%s Wrote the Falcon node sensor DaemonSet manifest to %s\n", tui.Green(tui.SuccessIcon), path)
+			fmt.Printf("  Deploy it to each cluster with: kubectl apply -f %s\n", filepath.Base(path))
+		}
+
+		return nil
+	},
+}
+
+func NewGKECmd() *cobra.Command {
+	return gkeCmd
+}
+
+// stageImage copies the node sensor image into the Artifact Registry repository.
+func stageImage(
+	ctx context.Context,
+	logger *slog.Logger,
+	client *falconclient.CrowdStrikeAPISpecification,
+	cloud falcon.CloudType,
+) (*sensor.Image, error) {
+	img := &sensor.Image{
+		Cloud:      cloud,
+		Repository: strings.TrimSuffix(artifactRegistry, "/"),
+		Logger:     logger,
+	}
+
+	p := tea.NewProgram(tui.ImageSyncModel{Image: img})
+	go func() {
+		p.Run()
+	}()
+
+	err := img.StageToArtifactRegistry(ctx, client, falconCid)
+	if err != nil {
+		p.Quit()
+		p.Wait()
+
+		if errors.Is(err, falconutil.ErrRegistryScope) {
+			fmt.Println(
+				errorsutil.DefaultError(
+					"Unable to pull the node sensor image. Add the Falcon Images Download (Read) scope to the API client.",
+					err,
+				),
+			)
+			return nil, err
+		}
+
+		fmt.Println(
+			errorsutil.DefaultError(
+				fmt.Sprintf("Unable to stage the node sensor image to %s.", artifactRegistry),
+				err,
+			),
+		)
+		return nil, err
+	}
+
+	p.Wait()
+
+	fmt.Printf("%s Staged the Falcon node sensor image %s\n", tui.Green(tui.SuccessIcon), img.Reference())
+	return img, nil
+}
+
+// detectCOS lists the Container-Optimized OS VMs in the targeted zones.
+func detectCOS(ctx context.Context, logger *slog.Logger) error {
+	targetProjects, err := gcputil.TargetProjects(projects, projectsFile)
+	if err != nil {
+		fmt.Println(
+			errorsutil.DefaultError(
+				fmt.Sprintf("Unable to read projects file (%s).", projectsFile),
+				err,
+			),
+		)
+		return err
+	}
+	errorsutil.AddSensitive(targetProjects...)
+
+	gcpClient, err := gcputil.NewClient(ctx, logger)
+	if err != nil {
+		fmt.Println(errorsutil.DefaultError("Unexpected error while creating gcp client.", err))
+		return err
+	}
+
+	targets, err := gcpClient.ResolveTargets(ctx, targetProjects, gcputil.ZoneSelector{
+		Zones:         zones,
+		Regions:       regions,
+		AllZones:      allZones,
+		WithInstances: zonesWithInstances,
+	})
+	if len(targets) == 0 && err != nil {
+		fmt.Println(errorsutil.DefaultError("Unable to determine the GCP compute zones to check.", err))
+		return err
+	}
+	tui.WarnFailedProjects(err)
+
+	oses, err := gcputil.ListZones(ctx, targets, gcpClient.InstanceOSes)
+	if err != nil {
+		fmt.Println(errorsutil.DefaultError("Unable to read the OS Config inventory.", err))
+		return err
+	}
+
+	var cos []gcputil.InstanceOS
+	for _, o := range oses {
+		if o.ShortName == gcputil.OSShortNameCOS {
+			cos = append(cos, o)
+		}
+	}
+
+	fmt.Println("")
+	if len(cos) == 0 {
+		fmt.Printf("No Container-Optimized OS VMs found in %d VM inventories.\n\n", len(oses))
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "PROJECT\tZONE\tINSTANCE\tHOSTNAME\tVERSION")
+	for _, o := range cos {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", o.Project, o.Zone, o.Instance, o.Hostname, o.Version)
+	}
+	w.Flush()
+
+	fmt.Printf(
+		"\n%s %d of %d VMs run Container-Optimized OS and are not covered by the OS Policy.\n\n",
+		tui.Yellow(tui.WarningIcon),
+		len(cos),
+		len(oses),
+	)
+	return nil
+}
+
+func init() {
+	gkeCmd.Flags().
+		StringVar(&falconClientId, "falcon-client-id", "", "Falcon API Client Id. Can also bet set by the FALCON_CLIENT_ID environment variable")
+	gkeCmd.Flags().
+		StringVar(&falconClientSecret, "falcon-client-secret", "", "Falcon API Client Secret. Can also bet set by the FALCON_CLIENT_SECRET environment variable")
+	gkeCmd.Flags().
+		StringVar(&falconCloud, "falcon-cloud", "", "Falcon Cloud one of autodiscover, us-1, us-2, eu-1, us-gov-1. Can also bet set by the FALCON_CLOUD environment variable")
+	gkeCmd.Flags().
+		StringVar(&falconCid, "falcon-cid", "", "Falcon CID the node sensor registers with. Can also bet set by the FALCON_CID environment variable. Will be pulled from the api if not provided")
+	gkeCmd.Flags().
+		StringVar(&image, "image", "", "Falcon node sensor image the nodes pull, including its tag or digest")
+	gkeCmd.Flags().
+		StringVar(&artifactRegistry, "artifact-registry", "", "Artifact Registry repository to stage the node sensor image in, e.g. us-docker.pkg.dev/my-project/falcon")
+	gkeCmd.Flags().
+		StringVar(&outputFormat, "format", kube.FormatDaemonSet, "Output format one of daemonset, helm")
+	gkeCmd.Flags().
+		StringVar(&outputFile, "output-file", "", "Output file. Defaults to falcon-node-sensor.yaml, or falcon-sensor-values.yaml with --format=helm")
+	gkeCmd.Flags().StringSliceVar(&zones, "zones", []string{}, "GCP compute zones to check for Container-Optimized OS VMs")
+	gkeCmd.Flags().
+		StringSliceVar(&regions, "regions", []string{}, "GCP compute regions to check. Expanded to every zone in the region")
+	gkeCmd.Flags().
+		BoolVar(&allZones, "all-zones", false, "Check every GCP compute zone available in the project")
+	gkeCmd.Flags().
+		BoolVar(&zonesWithInstances, "zones-with-instances", false, "Only check zones that contain Compute Engine VMs")
+	gkeCmd.Flags().
+		StringSliceVar(&projects, "projects", []string{}, "GCP projects to check. Defaults to the gcloud cli's active project")
+	gkeCmd.Flags().
+		StringVar(&projectsFile, "projects-file", "", "File containing GCP projects to check, one per line")
+	gkeCmd.Flags().
+		StringSliceVar(&sensorTags, "tags", []string{}, "Sensor grouping tags")
+	gkeCmd.Flags().
+		StringVar(&proxyHost, "proxy-host", "", "Proxy host the sensor connects through")
+	gkeCmd.Flags().
+		IntVar(&proxyPort, "proxy-port", 0, "Proxy port the sensor connects through. Requires --proxy-host")
+	gkeCmd.Flags().
+		BoolVar(&disableProxy, "disable-proxy", false, "Disable the sensor's proxy")
+	gkeCmd.Flags().
+		StringVar(&provisioningToken, "provisioning-token", "", "Sensor provisioning token. Can also bet set by the FALCON_PROVISIONING_TOKEN environment variable")
+	gkeCmd.Flags().
+		StringVar(&billing, "billing", "", fmt.Sprintf("Sensor billing type one of %s", strings.Join(policy.BillingTypes, ", ")))
+	gkeCmd.MarkFlagsOneRequired("image", "artifact-registry")
+	gkeCmd.MarkFlagsMutuallyExclusive("image", "artifact-registry")
+	gkeCmd.MarkFlagsMutuallyExclusive("all-zones", "zones")
+	gkeCmd.MarkFlagsMutuallyExclusive("all-zones", "regions")
+	gkeCmd.MarkFlagsMutuallyExclusive("disable-proxy", "proxy-host")
+	gkeCmd.MarkFlagsMutuallyExclusive("disable-proxy", "proxy-port")
+
+	if falconClientId == "" {
+		falconClientId = os.Getenv("FALCON_CLIENT_ID")
+	}
+
+	if falconClientSecret == "" {
+		falconClientSecret = os.Getenv("FALCON_CLIENT_SECRET")
+	}
+
+	if falconCloud == "" {
+		falconCloud = os.Getenv("FALCON_CLOUD")
+	}
+
+	if falconCid == "" {
+		falconCid = os.Getenv("FALCON_CID")
+	}
+
+	if provisioningToken == "" {
+		provisioningToken = os.Getenv("FALCON_PROVISIONING_TOKEN")
+	}
+}
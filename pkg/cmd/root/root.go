@@ -1,28 +1,104 @@
 package root
 
 import (
+	"fmt"
+	"io"
+	"log/slog"
 	"os"
+	"strings"
 
 	"github.com/MakeNowJust/heredoc"
+	"github.com/crowdstrike/gcp-os-policy/internal/errorsutil"
+	"github.com/crowdstrike/gcp-os-policy/internal/logging"
 	createCmd "github.com/crowdstrike/gcp-os-policy/pkg/cmd/create"
+	doctorCmd "github.com/crowdstrike/gcp-os-policy/pkg/cmd/doctor"
+	gkeCmd "github.com/crowdstrike/gcp-os-policy/pkg/cmd/gke"
+	statusCmd "github.com/crowdstrike/gcp-os-policy/pkg/cmd/status"
+	verifyCmd "github.com/crowdstrike/gcp-os-policy/pkg/cmd/verify"
 	"github.com/spf13/cobra"
 )
 
+var logLevel string
+var logFormat string
+var logFile string
+var debug bool
+var noIssueLink bool
+
+// logCloser closes the log file, if any, once the command completes.
+var logCloser io.Closer
+
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
 	Use:   "cs-policy <command> [flags]",
 	Short: "cs-policy CLI",
 	Example: heredoc.Doc(`
     $ cs-policy create --help
+    $ cs-policy doctor --help
+    $ cs-policy gke --help
+    $ cs-policy status --help
+    $ cs-policy verify --help
     `),
+	PersistentPreRunE: func(_ *cobra.Command, _ []string) error {
+		if debug {
+			logLevel = "debug"
+		}
+
+		logger, closer, err := logging.New(logging.Options{
+			Level:  logLevel,
+			Format: logFormat,
+			File:   logFile,
+		})
+		if err != nil {
+			return err
+		}
+
+		logCloser = closer
+		slog.SetDefault(logger)
+
+		if logFile == "" && strings.EqualFold(logLevel, "debug") {
+			fmt.Fprintf(os.Stderr, "Writing debug logs to %s\n", logging.DebugFile)
+		}
+
+		errorsutil.DisableIssueLink(noIssueLink)
+		errorsutil.AddSensitive(
+			os.Getenv("CLOUDSDK_CORE_PROJECT"),
+			os.Getenv("GOOGLE_CLOUD_PROJECT"),
+		)
+
+		return nil
+	},
 }
 
 // Execute adds all child commands to the root cs-policy setup and sets flags appropriately.
-func Execute() {
+func Execute(version string) {
+	rootCmd.Version = version
+	errorsutil.SetDiagnostic("Version", version)
 	rootCmd.AddCommand(createCmd.NewCreateCmd())
+	rootCmd.AddCommand(doctorCmd.NewDoctorCmd())
+	rootCmd.AddCommand(gkeCmd.NewGKECmd())
+	rootCmd.AddCommand(statusCmd.NewStatusCmd())
+	rootCmd.AddCommand(verifyCmd.NewVerifyCmd())
 
 	err := rootCmd.Execute()
+
+	if logCloser != nil {
+		logCloser.Close()
+	}
+
 	if err != nil {
 		os.Exit(1)
 	}
 }
+
+func init() {
+	rootCmd.PersistentFlags().
+		StringVar(&logLevel, "log-level", "warn", "Log level one of debug, info, warn, error")
+	rootCmd.PersistentFlags().
+		StringVar(&logFormat, "log-format", logging.FormatText, "Log format one of text, json")
+	rootCmd.PersistentFlags().
+		StringVar(&logFile, "log-file", "", "Write logs to the given file instead of stderr. Use - for stderr. Debug logs default to "+logging.DebugFile)
+	rootCmd.PersistentFlags().
+		BoolVar(&debug, "debug", false, "Enable debug logging. Shorthand for --log-level=debug")
+	rootCmd.PersistentFlags().
+		BoolVar(&noIssueLink, "no-issue-link", false, "Do not print a prefilled GitHub issue link when an error occurs")
+}
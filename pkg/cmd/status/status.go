@@ -0,0 +1,233 @@
+package status
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/MakeNowJust/heredoc"
+	"github.com/crowdstrike/gcp-os-policy/internal/errorsutil"
+	"github.com/crowdstrike/gcp-os-policy/internal/gcputil"
+	"github.com/crowdstrike/gcp-os-policy/internal/policy"
+	"github.com/crowdstrike/gcp-os-policy/internal/tui"
+	"github.com/spf13/cobra"
+	"golang.org/x/sync/errgroup"
+)
+
+var mode string
+var zones []string
+var regions []string
+var allZones bool
+var zonesWithInstances bool
+var projects []string
+var projectsFile string
+var showInstances bool
+
+// zoneStatus is the compliance of the VMs targeted by an assignment in a zone.
+type zoneStatus struct {
+	project   string
+	zone      string
+	installed int
+	missing   int
+	unknown   int
+	// missingInstances are the VMs missing the sensor.
+	missingInstances []string
+	err              error
+}
+
+// statusCmd represents the cs-policy status command
+var statusCmd = &cobra.Command{
+	Use:   "status [flags]",
+	Short: "Summarise the compliance of GCP OS Policy Assignments for Falcon Sensor deployment",
+	Long: `Summarise the compliance of GCP OS Policy Assignments for Falcon Sensor deployment
+
+  The OS Config compliance reports of every VM targeted by the assignments are counted per zone.
+  With --mode=validation the audit assignments are summarised, showing how many VMs the
+  sensor would be installed on when the policy is enforced.`,
+	Example: heredoc.Doc(`
+    Show which VMs in us-central1 are missing the sensor after creating audit assignments
+    $ cs-policy status --mode=validation --regions=us-central1 --show-instances
+    `),
+	Args:          cobra.ExactArgs(0),
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	RunE: func(_ *cobra.Command, _ []string) error {
+		logger := slog.Default()
+		ctx := context.Background()
+
+		policyMode, err := policy.ParseMode(mode)
+		if err != nil {
+			fmt.Println(err)
+			return err
+		}
+
+		targetProjects, err := gcputil.TargetProjects(projects, projectsFile)
+		if err != nil {
+			fmt.Println(
+				errorsutil.DefaultError(
+					fmt.Sprintf("Unable to read projects file (%s).", projectsFile),
+					err,
+				),
+			)
+			return err
+		}
+		errorsutil.AddSensitive(targetProjects...)
+
+		gcpClient, err := gcputil.NewClient(ctx, logger)
+		if err != nil {
+			fmt.Println(errorsutil.DefaultError("Unexpected error while creating gcp client.", err))
+			return err
+		}
+
+		targets, err := gcpClient.ResolveTargets(ctx, targetProjects, gcputil.ZoneSelector{
+			Zones:         zones,
+			Regions:       regions,
+			AllZones:      allZones,
+			WithInstances: zonesWithInstances,
+		})
+		if len(targets) == 0 && err != nil {
+			fmt.Println(
+				errorsutil.DefaultError("Unable to determine the GCP compute zones to summarise.", err),
+			)
+			return err
+		}
+		tui.WarnFailedProjects(err)
+
+		statuses := collect(ctx, gcpClient, targets, policy.AssignmentPrefix(policyMode))
+		printStatuses(statuses, policyMode == policy.ModeValidation)
+
+		return nil
+	},
+}
+
+func NewStatusCmd() *cobra.Command {
+	return statusCmd
+}
+
+// collect fetches the compliance reports of each zone concurrently.
+func collect(
+	ctx context.Context,
+	gcpClient *gcputil.Client,
+	targets map[string][]string,
+	namePrefix string,
+) []*zoneStatus {
+	var statuses []*zoneStatus
+
+	eg, egCtx := errgroup.WithContext(ctx)
+	eg.SetLimit(10)
+
+	for _, project := range gcputil.SortedProjects(targets) {
+		for _, z := range targets[project] {
+			zs := &zoneStatus{project: project, zone: z}
+			statuses = append(statuses, zs)
+
+			eg.Go(func() error {
+				a := policy.Assignment{Project: zs.project, Zone: zs.zone, NamePrefix: namePrefix}
+				reports, err := gcpClient.AssignmentReports(egCtx, zs.project, zs.zone, a.Name())
+				if err != nil {
+					zs.err = err
+					return nil
+				}
+
+				for _, r := range reports {
+					switch {
+					case r.State == gcputil.ComplianceUnknown:
+						zs.unknown++
+					case policy.SensorMissing(r.NonCompliantResources):
+						zs.missing++
+						zs.missingInstances = append(zs.missingInstances, r.Instance)
+					default:
+						zs.installed++
+					}
+				}
+
+				sort.Strings(zs.missingInstances)
+				return nil
+			})
+		}
+	}
+
+	eg.Wait()
+	return statuses
+}
+
+func printStatuses(statuses []*zoneStatus, validation bool) {
+	missingHeader := "MISSING SENSOR"
+	if validation {
+		missingHeader = "WOULD INSTALL"
+	}
+
+	fmt.Println("")
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "PROJECT\tZONE\tINSTALLED\t%s\tUNKNOWN\n", missingHeader)
+
+	var installed, missing, unknown int
+	var failed []*zoneStatus
+	for _, zs := range statuses {
+		if zs.err != nil {
+			failed = append(failed, zs)
+			fmt.Fprintf(w, "%s\t%s\t-\t-\t-\n", zs.project, zs.zone)
+			continue
+		}
+
+		installed += zs.installed
+		missing += zs.missing
+		unknown += zs.unknown
+		fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%d\n", zs.project, zs.zone, zs.installed, zs.missing, zs.unknown)
+	}
+	w.Flush()
+
+	total := installed + missing + unknown
+	fmt.Println("")
+	if validation {
+		fmt.Printf("Would install the Falcon sensor on %d of %d VMs.\n", missing, total)
+	} else {
+		fmt.Printf("The Falcon sensor is installed on %d of %d VMs, %d are missing it.\n", installed, total, missing)
+	}
+
+	if unknown > 0 {
+		fmt.Printf("%d VMs are in an unknown state, e.g. stopped or without the OS Config agent.\n", unknown)
+	}
+
+	if showInstances && missing > 0 {
+		fmt.Println("")
+		for _, zs := range statuses {
+			for _, i := range zs.missingInstances {
+				fmt.Printf("  %s/%s/%s\n", zs.project, zs.zone, i)
+			}
+		}
+	}
+
+	if len(failed) > 0 {
+		fmt.Println("")
+		for _, zs := range failed {
+			fmt.Printf("Unable to get compliance reports for %s/%s: %s\n", zs.project, zs.zone, strings.TrimSpace(zs.err.Error()))
+		}
+	}
+}
+
+func init() {
+	statusCmd.Flags().
+		StringVar(&mode, "mode", "enforcement", "Summarise the assignments created with this mode one of enforcement, validation")
+	statusCmd.Flags().StringSliceVar(&zones, "zones", []string{}, "GCP compute zones to summarise")
+	statusCmd.Flags().
+		StringSliceVar(&regions, "regions", []string{}, "GCP compute regions to summarise. Expanded to every zone in the region")
+	statusCmd.Flags().
+		BoolVar(&allZones, "all-zones", false, "Summarise every GCP compute zone available in the project")
+	statusCmd.Flags().
+		BoolVar(&zonesWithInstances, "zones-with-instances", false, "Only summarise zones that contain Compute Engine VMs")
+	statusCmd.Flags().
+		StringSliceVar(&projects, "projects", []string{}, "GCP projects to summarise. Defaults to the gcloud cli's active project")
+	statusCmd.Flags().
+		StringVar(&projectsFile, "projects-file", "", "File containing GCP projects to summarise, one per line")
+	statusCmd.Flags().
+		BoolVar(&showInstances, "show-instances", false, "List the VMs that are missing the sensor")
+	statusCmd.MarkFlagsOneRequired("zones", "regions", "all-zones", "zones-with-instances")
+	statusCmd.MarkFlagsMutuallyExclusive("all-zones", "zones")
+	statusCmd.MarkFlagsMutuallyExclusive("all-zones", "regions")
+}
@@ -0,0 +1,306 @@
+package verify
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/MakeNowJust/heredoc"
+	"github.com/crowdstrike/gcp-os-policy/internal/errorsutil"
+	"github.com/crowdstrike/gcp-os-policy/internal/falconutil"
+	"github.com/crowdstrike/gcp-os-policy/internal/gcputil"
+	"github.com/crowdstrike/gcp-os-policy/internal/logging"
+	"github.com/crowdstrike/gcp-os-policy/internal/policy"
+	"github.com/crowdstrike/gcp-os-policy/internal/tui"
+	"github.com/crowdstrike/gcp-os-policy/internal/verify"
+	"github.com/crowdstrike/gofalcon/falcon"
+	"github.com/spf13/cobra"
+)
+
+const (
+	formatTable = "table"
+	formatJSON  = "json"
+	formatCSV   = "csv"
+)
+
+var falconClientId string
+var falconClientSecret string
+var falconCloud string
+var zones []string
+var regions []string
+var allZones bool
+var zonesWithInstances bool
+var projects []string
+var projectsFile string
+var staleAfter time.Duration
+var outputFormat string
+var showAll bool
+var inclusionLabels []string
+var exclusionLabels []string
+
+// verifyCmd represents the cs-policy verify command
+var verifyCmd = &cobra.Command{
+	Use:   "verify [flags]",
+	Short: "Cross-check Compute Engine VMs against the hosts in Falcon",
+	Long: `Cross-check Compute Engine VMs against the hosts in Falcon
+
+  The running VMs in the targeted zones are matched to the Falcon hosts running in GCP by
+  instance id, falling back to the hostname. VMs without a Falcon host are reported as
+  missing and VMs whose host has not been seen within --stale-after as stale.
+
+  Only the VMs the OS Policy applies to are checked: Container-Optimized OS VMs are skipped
+  and --inclusion-labels and --exclusion-labels select VMs the same way as the policy.
+
+  Requires the Hosts (Read) scope.`,
+	Example: heredoc.Doc(`
+    Show the VMs in us-central1 that are missing from Falcon
+    $ cs-policy verify --regions=us-central1
+
+    Export every VM and its Falcon host as csv
+    $ cs-policy verify --all-zones --format=csv --all > hosts.csv
+
+    Only verify the production VMs that are not labeled falcon=skip
+    $ cs-policy verify --all-zones --inclusion-labels=env:prod --exclusion-labels=falcon:skip
+    `),
+	Args:          cobra.ExactArgs(0),
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	RunE: func(_ *cobra.Command, _ []string) error {
+		logger := slog.Default()
+		ctx := context.Background()
+
+		if outputFormat != formatTable && outputFormat != formatJSON && outputFormat != formatCSV {
+			err := fmt.Errorf("invalid format %q: must be one of table, json, csv", outputFormat)
+			fmt.Println(err)
+			return err
+		}
+
+		if falconCloud == "" {
+			falconCloud = "autodiscover"
+		}
+
+		cloud, err := falcon.CloudValidate(falconCloud)
+		if err != nil {
+			fmt.Println(
+				errorsutil.DefaultError(
+					fmt.Sprintf("Unable to validate %s as falcon cloud.", falconCloud),
+					err,
+				),
+			)
+			return err
+		}
+
+		targetProjects, err := gcputil.TargetProjects(projects, projectsFile)
+		if err != nil {
+			fmt.Println(
+				errorsutil.DefaultError(
+					fmt.Sprintf("Unable to read projects file (%s).", projectsFile),
+					err,
+				),
+			)
+			return err
+		}
+
+		errorsutil.AddSensitive(falconClientId, falconClientSecret)
+		errorsutil.AddSensitive(targetProjects...)
+
+		client, err := falcon.NewClient(&falcon.ApiConfig{
+			ClientId:          falconClientId,
+			ClientSecret:      falconClientSecret,
+			Cloud:             cloud,
+			Context:           ctx,
+			UserAgentOverride: "crowdstrike-gcp-vm-manager-os-policy/v0.0.2",
+			TransportDecorator: func(rt http.RoundTripper) http.RoundTripper {
+				return logging.NewTransport(rt, logger, "falcon")
+			},
+		})
+		if err != nil {
+			fmt.Println(errorsutil.DefaultError("Unexpected error while creating falcon client.", err))
+			return err
+		}
+
+		gcpClient, err := gcputil.NewClient(ctx, logger)
+		if err != nil {
+			fmt.Println(errorsutil.DefaultError("Unexpected error while creating gcp client.", err))
+			return err
+		}
+
+		targets, err := gcpClient.ResolveTargets(ctx, targetProjects, gcputil.ZoneSelector{
+			Zones:         zones,
+			Regions:       regions,
+			AllZones:      allZones,
+			WithInstances: zonesWithInstances,
+		})
+		if len(targets) == 0 && err != nil {
+			fmt.Println(
+				errorsutil.DefaultError("Unable to determine the GCP compute zones to verify.", err),
+			)
+			return err
+		}
+		tui.WarnFailedProjects(err)
+
+		instances, err := gcputil.ListZones(ctx, targets, gcpClient.Instances)
+		if err != nil {
+			fmt.Println(errorsutil.DefaultError("Unable to list the Compute Engine VMs.", err))
+			return err
+		}
+
+		hosts, err := falconutil.GCPHosts(client)
+		if errors.Is(err, falconutil.ErrHostsScope) {
+			fmt.Printf("Unable to list the Falcon hosts: %s. Add the scope to the API client in the Falcon console.\n", err)
+			return err
+		}
+		if err != nil {
+			fmt.Println(errorsutil.DefaultError("Unexpected error while listing the Falcon hosts.", err))
+			return err
+		}
+
+		instances = verify.Filter(instances, policy.InstanceFilter{
+			InclusionLabels: policy.ParseLabelSets(inclusionLabels),
+			ExclusionLabels: policy.ParseLabelSets(exclusionLabels),
+		})
+
+		logger.Info("matching instances to falcon hosts", "instances", len(instances), "hosts", len(hosts))
+		results := verify.Match(instances, hosts, staleAfter, time.Now())
+
+		switch outputFormat {
+		case formatJSON:
+			return writeJSON(os.Stdout, filterResults(results))
+		case formatCSV:
+			return writeCSV(os.Stdout, filterResults(results))
+		}
+
+		printResults(results)
+		return nil
+	},
+}
+
+func NewVerifyCmd() *cobra.Command {
+	return verifyCmd
+}
+
+// filterResults drops the VMs with a healthy Falcon host unless --all is set.
+func filterResults(results []verify.Result) []verify.Result {
+	if showAll {
+		return results
+	}
+
+	filtered := []verify.Result{}
+	for _, r := range results {
+		if r.Status != verify.StatusOK {
+			filtered = append(filtered, r)
+		}
+	}
+	return filtered
+}
+
+func lastSeen(r verify.Result) string {
+	if r.LastSeen == nil {
+		return ""
+	}
+	return r.LastSeen.UTC().Format(time.RFC3339)
+}
+
+func writeJSON(wr io.Writer, results []verify.Result) error {
+	enc := json.NewEncoder(wr)
+	enc.SetIndent("", "  ")
+	return enc.Encode(results)
+}
+
+func writeCSV(wr io.Writer, results []verify.Result) error {
+	w := csv.NewWriter(wr)
+	w.Write([]string{"project", "zone", "instance", "instance_id", "status", "aid", "last_seen"})
+	for _, r := range results {
+		w.Write([]string{r.Project, r.Zone, r.Instance, r.InstanceID, r.Status, r.AID, lastSeen(r)})
+	}
+	w.Flush()
+	return w.Error()
+}
+
+func printResults(results []verify.Result) {
+	counts := map[string]int{}
+	for _, r := range results {
+		counts[r.Status]++
+	}
+
+	filtered := filterResults(results)
+
+	fmt.Println("")
+
+	if len(filtered) > 0 {
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "PROJECT\tZONE\tINSTANCE\tINSTANCE ID\tSTATUS\tAID\tLAST SEEN")
+		for _, r := range filtered {
+			fmt.Fprintf(
+				w,
+				"%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+				r.Project, r.Zone, r.Instance, r.InstanceID, strings.ToUpper(r.Status), r.AID, lastSeen(r),
+			)
+		}
+		w.Flush()
+		fmt.Println("")
+	}
+
+	fmt.Printf(
+		"%d of %d VMs are in Falcon, %d are missing and %d have not been seen in %s.\n",
+		counts[verify.StatusOK]+counts[verify.StatusStale],
+		len(results),
+		counts[verify.StatusMissing],
+		counts[verify.StatusStale],
+		staleAfter,
+	)
+}
+
+func init() {
+	verifyCmd.Flags().
+		StringVar(&falconClientId, "falcon-client-id", "", "Falcon API Client Id. Can also bet set by the FALCON_CLIENT_ID environment variable")
+	verifyCmd.Flags().
+		StringVar(&falconClientSecret, "falcon-client-secret", "", "Falcon API Client Secret. Can also bet set by the FALCON_CLIENT_SECRET environment variable")
+	verifyCmd.Flags().
+		StringVar(&falconCloud, "falcon-cloud", "", "Falcon Cloud one of autodiscover, us-1, us-2, eu-1, us-gov-1. Can also bet set by the FALCON_CLOUD environment variable")
+	verifyCmd.Flags().StringSliceVar(&zones, "zones", []string{}, "GCP compute zones to verify")
+	verifyCmd.Flags().
+		StringSliceVar(&regions, "regions", []string{}, "GCP compute regions to verify. Expanded to every zone in the region")
+	verifyCmd.Flags().
+		BoolVar(&allZones, "all-zones", false, "Verify every GCP compute zone available in the project")
+	verifyCmd.Flags().
+		BoolVar(&zonesWithInstances, "zones-with-instances", false, "Only verify zones that contain Compute Engine VMs")
+	verifyCmd.Flags().
+		StringSliceVar(&projects, "projects", []string{}, "GCP projects to verify. Defaults to the gcloud cli's active project")
+	verifyCmd.Flags().
+		StringVar(&projectsFile, "projects-file", "", "File containing GCP projects to verify, one per line")
+	verifyCmd.Flags().
+		DurationVar(&staleAfter, "stale-after", 24*time.Hour, "Report Falcon hosts that have not been seen for this long as stale")
+	verifyCmd.Flags().
+		StringVar(&outputFormat, "format", formatTable, "Output format one of table, json, csv")
+	verifyCmd.Flags().
+		BoolVar(&showAll, "all", false, "Include the VMs whose Falcon host was seen recently")
+	verifyCmd.Flags().
+		StringArrayVar(&inclusionLabels, "inclusion-labels", []string{}, "Only verify VMs that have all the labels of any of the label sets. In the format of labelName:labelValue,labelName:labelValue")
+	verifyCmd.Flags().
+		StringArrayVar(&exclusionLabels, "exclusion-labels", []string{}, "Skip VMs that have all the labels of any of the label sets. In the format of labelName:labelValue,labelName:labelValue")
+	verifyCmd.MarkFlagsOneRequired("zones", "regions", "all-zones", "zones-with-instances")
+	verifyCmd.MarkFlagsMutuallyExclusive("all-zones", "zones")
+	verifyCmd.MarkFlagsMutuallyExclusive("all-zones", "regions")
+
+	if falconClientId == "" {
+		falconClientId = os.Getenv("FALCON_CLIENT_ID")
+	}
+
+	if falconClientSecret == "" {
+		falconClientSecret = os.Getenv("FALCON_CLIENT_SECRET")
+	}
+
+	if falconCloud == "" {
+		falconCloud = os.Getenv("FALCON_CLOUD")
+	}
+}